@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/geoh/how/internal/config"
+)
+
+// configActions are the actions accepted by `how config`
+var configActions = []string{"get", "set", "list", "edit"}
+
+// runConfig handles `how config get|set|list|edit`
+func runConfig(args []string) error {
+	switch args[0] {
+	case "list":
+		cfg, err := config.Load(nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if cfg.ProjectFile != "" {
+			fmt.Printf("# project file: %s\n", cfg.ProjectFile)
+		}
+		for _, key := range config.Keys() {
+			value, _ := cfg.Get(key)
+			fmt.Printf("%s = %s (%s)\n", key, value, cfg.Source(key))
+		}
+		return nil
+
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: how config get <key>")
+		}
+		cfg, err := config.Load(nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		value, err := cfg.Get(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: how config set <key> <value>")
+		}
+		if err := config.SaveSetting(args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("%s set to %s in %s\n", args[1], args[2], config.UserConfigFile())
+		return nil
+
+	case "edit":
+		return editFile(config.UserConfigFile())
+	}
+
+	return fmt.Errorf("unknown config action %q", args[0])
+}
+
+// editFile opens path in the user's editor, creating it if needed
+func editFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return err
+		}
+	}
+	return openEditor(path, os.Stdin)
+}
+
+// openEditor runs $VISUAL or $EDITOR on path, reading keys from stdin
+func openEditor(path string, stdin *os.File) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		} else {
+			editor = "vi"
+		}
+	}
+
+	// The editor may carry its own arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -11,7 +11,11 @@ import (
 	"github.com/geoh/how/internal/clipboard"
 	"github.com/geoh/how/internal/config"
 	"github.com/geoh/how/internal/context"
+	"github.com/geoh/how/internal/integration"
+	"github.com/geoh/how/internal/prompt"
+	"github.com/geoh/how/internal/redact"
 	"github.com/geoh/how/internal/ui"
+	"github.com/geoh/how/internal/validate"
 )
 
 func main() {
@@ -19,11 +23,23 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-sigChan
-		fmt.Println("\n👋 Interrupted.")
-		os.Exit(130)
+		for range sigChan {
+			// A command started with --run handles its own interrupts
+			if commandRunning.Load() {
+				continue
+			}
+			fmt.Println("\n👋 Interrupted.")
+			os.Exit(130)
+		}
 	}()
 
+	// Move files left by older versions in ~/.how-cli
+	if moved, err := config.MigrateLegacy(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if moved {
+		fmt.Fprintln(os.Stderr, "Moved settings from ~/.how-cli to the XDG base directories.")
+	}
+
 	// Check for help flag or no arguments
 	if len(os.Args) < 2 || hasFlag("--help") {
 		ui.Header()
@@ -31,6 +47,51 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle `how profile ...`
+	if isSubcommand("profile", profileActions) {
+		if err := runProfile(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Handle `how config ...`
+	if isSubcommand("config", configActions) {
+		if err := runConfig(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Handle `how init <shell>`
+	if isSubcommand("init", integration.Shells()) {
+		script, err := integration.Script(os.Args[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
+
+	// Load settings; flags take precedence over every other layer
+	overrides := map[string]string{}
+	if hasFlag("--type") {
+		overrides["typewriter.enabled"] = "true"
+	}
+	if model := flagValue("--model"); model != "" {
+		overrides["model"] = model
+	}
+	if profile := flagValue("--profile"); profile != "" {
+		overrides["profile"] = profile
+	}
+	cfg, err := config.Load(overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	// Handle --history flag
 	if hasFlag("--history") {
 		if err := config.ShowHistory(); err != nil {
@@ -49,76 +110,133 @@ func main() {
 				fmt.Println("Error: API key cannot be empty.")
 				os.Exit(1)
 			}
-			if err := config.SaveAPIKey(newKey); err != nil {
+			if err := config.SaveAPIKey(cfg, newKey); err != nil {
 				fmt.Fprintf(os.Stderr, "Error saving API key: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Println("Gemini API key replaced successfully.")
+			if cfg.Profile != "" {
+				fmt.Printf("API key for profile %s replaced successfully.\n", cfg.Profile)
+			} else {
+				fmt.Println("Gemini API key replaced successfully.")
+			}
 			os.Exit(0)
 		}
 	}
 
 	// Parse flags
 	silent := hasFlag("--silent")
-	typeEffect := hasFlag("--type") && !silent
+	runMode := hasFlag("--run")
+	yesReally := hasFlag("--yes-really")
 
-	// Get question from arguments (excluding flags)
+	typeEffect := cfg.Typewriter.Enabled && !silent
+
+	// `how context` and --show-context preview the context and prompt;
+	// --dry-run previews the prompt. Neither calls the API.
 	args := filterFlags(os.Args[1:])
-	if len(args) == 0 {
+	contextCommand := len(args) == 1 && args[0] == "context"
+	showContext := hasFlag("--show-context") || contextCommand
+	dryRun := hasFlag("--dry-run") || showContext
+
+	// Text piped to how, e.g. `cat error.log | how to fix this`
+	var input string
+	if cfg.Stdin.Enabled {
+		var idle bool
+		input, idle, err = readPipedInput(cfg.Stdin.MaxBytes, cfg.Stdin.Timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if idle {
+			ui.Warning(fmt.Sprintf("Stdin was silent for %s; answering from the input read so far (raise stdin.timeout to wait longer).", cfg.Stdin.Timeout))
+		}
+	}
+
+	// The question is the arguments left after the flags
+	if len(args) == 0 && !showContext && input == "" {
 		fmt.Println("Error: No question provided.")
 		os.Exit(1)
 	}
 	question := strings.Join(args, " ")
+	if question == "" && input != "" {
+		question = "Explain this input. If it shows an error, give the command that fixes it."
+	}
+
+	// `how fix` asks for a correction of the last command, which the shell
+	// integration records
+	fixMode := len(args) == 1 && args[0] == "fix"
+	enabled := cfg.Context.Enabled
+	if fixMode {
+		enabled = append(enabled, "last_command")
+	}
+
+	if showContext && (len(args) == 0 || contextCommand) {
+		question = "<your question>"
+	}
 
 	// Get or create API key
-	apiKey, err := config.GetOrCreateAPIKey(false)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Authentication Error: %v\n", err)
-		os.Exit(1)
+	var apiKey string
+	if !dryRun && api.NeedsAPIKey(cfg.Provider) {
+		apiKey, err = config.GetOrCreateAPIKey(cfg, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Authentication Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Gather system context
-	ctx, err := context.Gather()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to gather system context: %v\n", err)
-		// Continue with default values
-		ctx = &context.SystemContext{
-			OS:             "Unknown",
-			Shell:          "Unknown",
-			CurrentDir:     "Unknown",
-			User:           "Unknown",
-			GitRepo:        "No",
-			Files:          "Unknown",
-			InstalledTools: "Unknown",
+	cacheDir, _ := config.CacheDir()
+	ctx := context.Gather(context.Options{
+		Disabled:       cfg.Context.Disabled,
+		Enabled:        enabled,
+		Timeout:        cfg.Context.Timeout,
+		Timeouts:       cfg.Context.Timeouts,
+		MaxFiles:       cfg.Context.MaxFiles,
+		FilesBudget:    cfg.Context.FilesBudget,
+		HistoryEntries: cfg.Context.HistoryEntries,
+		EnvAllow:       cfg.Context.EnvAllow,
+		EnvDeny:        cfg.Context.EnvDeny,
+		Tools:          cfg.Context.Tools,
+		ExtraTools:     cfg.Context.ExtraTools,
+		CacheDir:       cacheDir,
+	})
+	if cfg.Debug {
+		for _, section := range ctx.Sections {
+			if section.Err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s context: %v\n", section.Name, section.Err)
+			}
+		}
+	}
+
+	historyQuestion := question
+	if fixMode {
+		last, ok := ctx.Get("last_command").(context.LastCommandInfo)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", context.ErrNoLastCommand)
+			os.Exit(1)
 		}
+		question = prompt.FixQuestion(last)
+		historyQuestion = "fix: " + last.Command
 	}
 
 	// Build the prompt
-	prompt := fmt.Sprintf(`SYSTEM:
-You are an expert, concise shell assistant. Your goal is to provide accurate, executable shell commands.
-
-CONTEXT:
--   **OS:** %s
--   **Shell:** %s
--   **CWD:** %s
--   **User:** %s
--   **Git Repo:** %s
--   **Files (top 20):** %s
--   **Available Tools:** %s
-
-RULES:
-1.  **Primary Goal:** Generate *only* the exact, executable shell command(s) for the %s environment.
-2.  **Context is Key:** Use the CONTEXT (CWD, Files, OS) to write specific, correct commands.
-3.  **No Banter:** Do NOT include greetings, sign-offs, or conversational filler (e.g., "Here is the command:").
-4.  **Safety:** If a command is complex or destructive (e.g., ` + "`rm -rf`, `find -delete`" + `), add a single-line comment (` + "`# ...`" + `) *after* the command explaining what it does.
-5.  **Questions:** If the user asks a question (e.g., "what is ` + "`ls`" + `?"), provide a concise, one-line answer. Do not output a command.
-6.  **Ambiguity:** If the request is unclear, ask a single, direct clarifying question. Start the line with ` + "`#`" + `.
-
-REQUEST:
-%s
-
-RESPONSE:
-`, ctx.OS, ctx.Shell, ctx.CurrentDir, ctx.User, ctx.GitRepo, ctx.Files, ctx.InstalledTools, ctx.Shell, question)
+	promptText := prompt.Build(prompt.Params{
+		Question:     question,
+		Context:      ctx,
+		Facts:        cfg.Facts,
+		Instructions: cfg.Prompt,
+		Input:        input,
+	})
+
+	// Remove secrets before anything leaves the machine
+	redactor, err := redact.New(cfg.Redact.Patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	promptText, redactions := redactor.Redact(promptText)
+
+	if dryRun {
+		printPreview(ctx, redactor, promptText, redactions, showContext)
+		os.Exit(0)
+	}
 
 	// Generate response with spinner
 	var spinner *ui.Spinner
@@ -127,7 +245,17 @@ RESPONSE:
 		spinner.Start()
 	}
 
-	text, err := api.GenerateResponse(apiKey, prompt, 3)
+	apiOpts := api.Options{
+		Provider: cfg.Provider,
+		Endpoint: cfg.Endpoint,
+		Model:    cfg.Model,
+		Retries:  cfg.Retries,
+		Timeout:  cfg.Timeout,
+	}
+	if cfg.Source("temperature") != config.SourceDefault {
+		apiOpts.Temperature = &cfg.Temperature
+	}
+	text, err := api.GenerateResponse(apiKey, promptText, apiOpts)
 
 	if !silent && spinner != nil {
 		spinner.Stop()
@@ -149,18 +277,7 @@ RESPONSE:
 		os.Exit(1)
 	}
 
-	// Clean the response
-	cleanedText := ui.CleanResponse(text)
-	commands := strings.Split(cleanedText, "\n")
-
-	// Filter out empty lines
-	var filteredCommands []string
-	for _, cmd := range commands {
-		if trimmed := strings.TrimSpace(cmd); trimmed != "" {
-			filteredCommands = append(filteredCommands, trimmed)
-		}
-	}
-
+	filteredCommands := responseCommands(text)
 	if len(filteredCommands) == 0 {
 		fmt.Println("⚠️ No valid commands generated.")
 		os.Exit(1)
@@ -168,37 +285,148 @@ RESPONSE:
 
 	fullCommand := strings.Join(filteredCommands, "\n")
 
+	// Check the command parses in the user's shell and calls programs that
+	// exist, asking the model once for a correction if enabled
+	shell, _ := ctx.Get("shell").(context.ShellInfo)
+	var issues []validate.Issue
+	if cfg.Validation.Enabled {
+		opts := validateOptions(shell)
+		issues = checkCommand(fullCommand, opts)
+		if cfg.Validation.Retry {
+			command, correctedIssues, err := correctCommand(fullCommand, issues, opts, func(problems []string) (string, error) {
+				if !silent {
+					spinner = ui.NewSpinner("Correcting")
+					spinner.Start()
+					defer spinner.Stop()
+				}
+				corrected, err := api.GenerateResponse(apiKey, prompt.Correction(promptText, text, problems), apiOpts)
+				return strings.Join(responseCommands(corrected), "\n"), err
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not get a corrected command: %v\n", err)
+			}
+			if command != fullCommand {
+				filteredCommands = strings.Split(command, "\n")
+			}
+			fullCommand, issues = command, correctedIssues
+		}
+	}
+
 	// Print the result
 	if typeEffect {
-		ui.TypewriterPrint(fullCommand)
+		ui.TypewriterPrint(fullCommand, cfg.Typewriter.Delay)
 	} else {
 		fmt.Println(fullCommand)
 	}
-
-	// Copy to clipboard
-	if err := clipboard.CopyToClipboard(fullCommand); err != nil {
-		// Only show clipboard error in verbose mode or if DISPLAY is set
-		if os.Getenv("DISPLAY") != "" || os.Getenv("HOW_DEBUG") != "" {
-			fmt.Fprintf(os.Stderr, "Warning: Could not copy to clipboard: %v\n", err)
+	for _, issue := range issues {
+		ui.Warning("⚠️  " + issue.String())
+	}
+	printWarnings(fullCommand)
+
+	// With --run the user picks what happens to the command
+	var result runResult
+	var runErr error
+	if runMode {
+		result, runErr = promptRun(fullCommand, shell, yesReally)
+		if runErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", runErr)
+		}
+		if result.Command != fullCommand {
+			filteredCommands = strings.Split(result.Command, "\n")
+		}
+	} else if cfg.Clipboard.Enabled {
+		// Copy to clipboard
+		if err := clipboard.CopyToClipboard(fullCommand); err != nil {
+			// Only show clipboard error in verbose mode or if DISPLAY is set
+			if os.Getenv("DISPLAY") != "" || cfg.Debug {
+				fmt.Fprintf(os.Stderr, "Warning: Could not copy to clipboard: %v\n", err)
+			}
 		}
 	}
 
 	// Log to history
-	if err := config.LogHistory(question, filteredCommands); err != nil {
-		// Just log a warning, don't fail
-		fmt.Fprintf(os.Stderr, "Warning: Failed to write history: %v\n", err)
+	if cfg.History.Enabled {
+		entry := config.HistoryEntry{
+			Question: historyQuestion,
+			Commands: filteredCommands,
+			Profile:  cfg.Profile,
+		}
+		if result.Ran {
+			entry.ExitCode = &result.ExitCode
+		}
+		if err := config.LogHistory(entry, cfg.History.MaxEntries); err != nil {
+			// Just log a warning, don't fail
+			fmt.Fprintf(os.Stderr, "Warning: Failed to write history: %v\n", err)
+		}
+	}
+
+	// Pass on the exit status of a command that was run
+	if result.Ran {
+		os.Exit(result.ExitCode)
+	}
+	if runErr != nil {
+		os.Exit(1)
+	}
+}
+
+// responseCommands cleans a model response into its non-empty lines
+func responseCommands(text string) []string {
+	var commands []string
+	for _, line := range strings.Split(ui.CleanResponse(text), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			commands = append(commands, trimmed)
+		}
 	}
+	return commands
 }
 
 func printHelp() {
-	fmt.Println("Usage: how <question> [--silent] [--history] [--type] [--help] [--api-key]")
+	fmt.Println("Usage: how <question> [--silent] [--history] [--type] [--model <name>] [--profile <name>] [--run] [--yes-really] [--dry-run] [--show-context] [--help] [--api-key]")
+	fmt.Println("       how config get|set|list|edit")
+	fmt.Println("       how profile list|use|show")
+	fmt.Println("       how init bash|fish|zsh")
+	fmt.Println("       how context")
+	fmt.Println("       how fix")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --silent      Suppress spinner and typewriter effect")
 	fmt.Println("  --type        Show output with typewriter effect")
+	fmt.Println("  --model       Use a different model for this question (usage: --model <name>)")
+	fmt.Println("  --profile     Use a named profile for this question (usage: --profile <name>)")
+	fmt.Println("  --run         Ask whether to execute, copy, modify or abort the command")
+	fmt.Println("  --yes-really  Allow --run to execute commands flagged as destructive")
+	fmt.Println("  --dry-run     Print the prompt that would be sent and exit")
+	fmt.Println("  --show-context  Print each context collector's output and the prompt, and exit")
 	fmt.Println("  --history     Show command/question history")
 	fmt.Println("  --help        Show this help message and exit")
-	fmt.Println("  --api-key     Set the Gemini API key (usage: --api-key <API_KEY>)")
+	fmt.Println("  --api-key     Set the API key of the active profile (usage: --api-key <API_KEY>)")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  config list             Show all settings and where they come from")
+	fmt.Println("  config get <key>        Show the effective value of a setting")
+	fmt.Println("  config set <key> <val>  Store a setting in the user config file")
+	fmt.Println("  config edit             Open the user config file in $EDITOR")
+	fmt.Println("  profile list            Show all profiles, marking the active one")
+	fmt.Println("  profile use <name>      Make a profile active by default")
+	fmt.Println("  profile show [name]     Show the settings of a profile")
+	fmt.Println("  init <shell>            Print the shell integration script")
+	fmt.Println("  context                 Preview the context and prompt without calling the API")
+	fmt.Println("  fix                     Correct the last command (needs the shell integration)")
+}
+
+// isSubcommand reports whether the arguments are `how <name> <action>`
+// with a known action, so questions that merely start with the same word
+// are still sent to the model
+func isSubcommand(name string, actions []string) bool {
+	if len(os.Args) < 3 || os.Args[1] != name {
+		return false
+	}
+	for _, action := range actions {
+		if os.Args[2] == action {
+			return true
+		}
+	}
+	return false
 }
 
 func hasFlag(flag string) bool {
@@ -219,6 +447,15 @@ func findFlagIndex(flag string) int {
 	return -1
 }
 
+// flagValue returns the argument following flag, or "" if there is none
+func flagValue(flag string) string {
+	idx := findFlagIndex(flag)
+	if idx != -1 && len(os.Args) > idx+1 && !strings.HasPrefix(os.Args[idx+1], "--") {
+		return strings.TrimSpace(os.Args[idx+1])
+	}
+	return ""
+}
+
 func filterFlags(args []string) []string {
 	var result []string
 	skipNext := false
@@ -229,12 +466,12 @@ func filterFlags(args []string) []string {
 			continue
 		}
 
-		if arg == "--silent" || arg == "--history" || arg == "--type" {
+		if arg == "--silent" || arg == "--history" || arg == "--type" || arg == "--dry-run" || arg == "--show-context" || arg == "--run" || arg == "--yes-really" {
 			continue
 		}
 
-		if arg == "--api-key" {
-			// Skip this flag and the next argument (the API key value)
+		if arg == "--api-key" || arg == "--model" || arg == "--profile" {
+			// Skip this flag and the next argument (its value)
 			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
 				skipNext = true
 			}
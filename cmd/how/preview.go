@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/geoh/how/internal/context"
+	"github.com/geoh/how/internal/redact"
+)
+
+// printPreview shows what would be sent to the model: with sections, each
+// collector's output first, then the redactions and the final prompt
+func printPreview(ctx *context.SystemContext, redactor *redact.Redactor, promptText string, redactions []redact.Redaction, sections bool) {
+	if sections {
+		fmt.Println("=== Context collectors ===")
+		ran := map[string]bool{}
+		for _, section := range ctx.Sections {
+			ran[section.Name] = true
+			fmt.Printf("\n[%s] %s\n", section.Name, section.Duration.Round(100*time.Microsecond))
+			if section.Err != nil {
+				fmt.Printf("  error: %v\n", section.Err)
+			}
+			if section.Data == nil {
+				continue
+			}
+			for _, fact := range section.Data.Facts() {
+				value, _ := redactor.Redact(fact.Value)
+				fmt.Printf("  %s: %s\n", fact.Label, value)
+			}
+		}
+		for _, c := range context.Collectors() {
+			if !ran[c.Name] {
+				fmt.Printf("\n[%s] not run (disabled or opt-in)\n", c.Name)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("=== Redactions ===")
+	if len(redactions) == 0 {
+		fmt.Println("none")
+	} else {
+		var applied []string
+		for _, r := range redactions {
+			applied = append(applied, r.String())
+		}
+		fmt.Println(strings.Join(applied, ", "))
+	}
+
+	fmt.Println()
+	fmt.Println("=== Prompt ===")
+	fmt.Println(promptText)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/geoh/how/internal/config"
+)
+
+// profileActions are the actions accepted by `how profile`
+var profileActions = []string{"list", "use", "show"}
+
+// runProfile handles `how profile list|use|show`
+func runProfile(args []string) error {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	switch args[0] {
+	case "list":
+		names := cfg.ProfileNames()
+		if len(names) == 0 {
+			fmt.Printf("No profiles defined. Add [profiles.<name>] tables to %s.\n", config.UserConfigFile())
+			return nil
+		}
+		for _, name := range names {
+			marker := " "
+			if name == cfg.Profile {
+				marker = "*"
+			}
+			p := cfg.Profiles[name]
+			fmt.Printf("%s %s (%s %s)\n", marker, name, cfg.ProfileProvider(name), valueOr(p.Model, "default model"))
+		}
+		return nil
+
+	case "use":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: how profile use <name>")
+		}
+		if _, ok := cfg.Profiles[args[1]]; !ok {
+			return fmt.Errorf("unknown profile %q", args[1])
+		}
+		if err := config.SaveSetting("profile", args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Now using profile %s.\n", args[1])
+		return nil
+
+	case "show":
+		name := cfg.Profile
+		if len(args) > 1 {
+			name = args[1]
+		}
+		if name == "" {
+			return fmt.Errorf("no active profile; usage: how profile show <name>")
+		}
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+		fmt.Printf("[profiles.%s]\n", name)
+		printSetting("provider", p.Provider)
+		printSetting("endpoint", p.Endpoint)
+		printSetting("model", p.Model)
+		printSetting("api_key_env", p.APIKeyEnv)
+		printSetting("api_key_command", p.APIKeyCommand)
+		printSetting("prompt", p.Prompt)
+		return nil
+	}
+
+	return fmt.Errorf("unknown profile action %q", args[0])
+}
+
+// printSetting prints a profile field if it is set
+func printSetting(key, value string) {
+	if value != "" {
+		fmt.Printf("%s = %q\n", key, value)
+	}
+}
+
+// valueOr returns value, or fallback when value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
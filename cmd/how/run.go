@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/geoh/how/internal/clipboard"
+	"github.com/geoh/how/internal/context"
+	"github.com/geoh/how/internal/integration"
+	"github.com/geoh/how/internal/platform"
+	"github.com/geoh/how/internal/safety"
+	"github.com/geoh/how/internal/ui"
+)
+
+// commandRunning is set while a generated command runs, so an interrupt
+// reaches the command without also killing how
+var commandRunning atomic.Bool
+
+// runResult is what happened at the run prompt
+type runResult struct {
+	// Command is the command as run or copied, after any modification
+	Command string
+	Ran     bool
+	// ExitCode is the exit status of the command when it ran
+	ExitCode int
+}
+
+// promptRun asks whether to execute, copy, modify or abort command and
+// acts on the answer. Destructive commands only run with yesReally and a
+// typed confirmation. The prompt reads from the terminal, so it works
+// while stdin is a pipe.
+func promptRun(command string, shell context.ShellInfo, yesReally bool) (runResult, error) {
+	tty, err := platform.OpenTerminal()
+	if err != nil {
+		return runResult{}, fmt.Errorf("--run needs a terminal to confirm: %v", err)
+	}
+	defer tty.Close()
+	reader := bufio.NewReader(tty)
+
+	result := runResult{Command: command}
+	for {
+		if isComment(result.Command) {
+			fmt.Fprintln(os.Stderr, "Nothing to run.")
+			return result, nil
+		}
+
+		fmt.Fprint(os.Stderr, "[E]xecute / [C]opy / [M]odify / [A]bort? ")
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return result, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "e", "execute":
+			if len(safety.Check(result.Command)) > 0 {
+				if !yesReally {
+					ui.Warning("Refusing to run a destructive command without --yes-really.")
+					continue
+				}
+				fmt.Fprint(os.Stderr, "⚠️  This command was flagged as destructive. Type 'yes' to run it: ")
+				answer, err := reader.ReadString('\n')
+				if err != nil {
+					return result, err
+				}
+				if strings.TrimSpace(answer) != "yes" {
+					fmt.Fprintln(os.Stderr, "Aborted.")
+					return result, nil
+				}
+			}
+			result.ExitCode, err = execute(result.Command, shell, tty)
+			result.Ran = err == nil
+			return result, err
+
+		case "c", "copy":
+			if err := clipboard.CopyToClipboard(result.Command); err != nil {
+				return result, fmt.Errorf("could not copy to clipboard: %v", err)
+			}
+			fmt.Fprintln(os.Stderr, "Copied to clipboard.")
+			return result, nil
+
+		case "m", "modify":
+			modified, err := modify(result.Command, tty)
+			if err != nil {
+				return result, err
+			}
+			result.Command = modified
+			fmt.Println(result.Command)
+			printWarnings(result.Command)
+
+		case "a", "abort", "":
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return result, nil
+		}
+	}
+}
+
+// execute runs command in shell with how's environment and working
+// directory, streaming its output, and returns its exit status
+func execute(command string, shell context.ShellInfo, tty *os.File) (int, error) {
+	name, args := shellInvocation(shell, command)
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	if !platform.StdinIsTerminal() {
+		// Piped input was already read as part of the question
+		cmd.Stdin = tty
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = commandEnv(os.Environ())
+
+	commandRunning.Store(true)
+	defer commandRunning.Store(false)
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Report a command killed by a signal as shells do
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not run %s: %v", name, err)
+	}
+	return 0, nil
+}
+
+// commandEnv returns env without the variables set by the shell
+// integration, which would otherwise reach a how run by the command and
+// describe the wrong shell state
+func commandEnv(env []string) []string {
+	var kept []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(integration.Env, name) {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// userShell returns the name and path of the detected shell, or of $SHELL
+// when detection did not run or failed. The name is "" or "." when neither
+// is known.
+func userShell(shell context.ShellInfo) (name, path string) {
+	// The shell collector reports "Unknown" when detection fails
+	if shell.Name == "" || shell.Name == "Unknown" {
+		path = os.Getenv("SHELL")
+		return strings.TrimSuffix(filepath.Base(path), ".exe"), path
+	}
+	return shell.Name, shell.Path
+}
+
+// shellInvocation returns the program and arguments that run command in
+// shell, falling back to $SHELL and then the platform's default shell
+func shellInvocation(shell context.ShellInfo, command string) (string, []string) {
+	name, path := userShell(shell)
+	if name == "" || name == "." {
+		if runtime.GOOS == "windows" {
+			name, path = "cmd", os.Getenv("ComSpec")
+		} else {
+			name, path = "sh", "/bin/sh"
+		}
+	}
+	if path == "" {
+		path = name
+	}
+
+	switch name {
+	case "powershell", "pwsh":
+		return path, []string{"-NoProfile", "-Command", command}
+	case "cmd":
+		// cmd runs only the first line of a /C argument
+		return path, []string{"/C", strings.ReplaceAll(command, "\n", " & ")}
+	default:
+		return path, []string{"-c", command}
+	}
+}
+
+// modify lets the user edit command in their editor
+func modify(command string, tty *os.File) (string, error) {
+	f, err := os.CreateTemp("", "how-*.sh")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(command + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := openEditor(f.Name(), tty); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// printWarnings shows what the safety check found in command
+func printWarnings(command string) {
+	for _, w := range safety.Check(command) {
+		ui.Warning("⚠️  " + w.String())
+	}
+}
+
+// isComment reports whether command is only comments, e.g. an answer to a
+// question or a clarifying question
+func isComment(command string) bool {
+	for _, line := range strings.Split(command, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
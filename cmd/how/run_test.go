@@ -0,0 +1,21 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommandEnv(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin",
+		"HOW_SHELL=bash",
+		"HOW_SHELL_HISTORY=  1  ls",
+		"HOW_LAST_COMMAND=ls",
+		"HOW_MODEL=gemini-2.5-pro",
+		"HOME=/home/me",
+	}
+	want := []string{"PATH=/usr/bin", "HOW_MODEL=gemini-2.5-pro", "HOME=/home/me"}
+	if got := commandEnv(env); !slices.Equal(got, want) {
+		t.Errorf("commandEnv() = %q, want %q", got, want)
+	}
+}
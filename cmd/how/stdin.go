@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/geoh/how/internal/platform"
+)
+
+// readPipedInput returns what was piped to how, or "" when stdin is a
+// terminal or anything else that may never end. Reading a pipe stops once
+// it has been silent for timeout, if set, which is reported as idle and
+// marked in the input. A file is read to the end. Input over maxBytes keeps its start, where headers are, and its
+// end, where errors usually are.
+func readPipedInput(maxBytes int, timeout time.Duration) (input string, idle bool, err error) {
+	if !platform.StdinIsPiped() {
+		return "", false, nil
+	}
+
+	if platform.StdinIsFile() {
+		timeout = 0
+	}
+	data, idle, err := readUntilIdle(os.Stdin, timeout)
+	if err != nil {
+		return "", false, fmt.Errorf("could not read stdin: %v", err)
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return "", false, fmt.Errorf("ignoring binary input on stdin")
+	}
+
+	data = bytes.TrimSpace(data)
+	if maxBytes > 0 && len(data) > maxBytes {
+		head := data[:maxBytes/4]
+		tail := data[len(data)-(maxBytes-len(head)):]
+		// Cut at line boundaries when possible
+		if i := bytes.LastIndexByte(head, '\n'); i > 0 {
+			head = head[:i]
+		}
+		if i := bytes.IndexByte(tail, '\n'); i != -1 && i < len(tail)-1 {
+			tail = tail[i+1:]
+		}
+		omitted := len(data) - len(head) - len(tail)
+		data = []byte(fmt.Sprintf("%s\n[... %d bytes omitted ...]\n%s", head, omitted, tail))
+	}
+	if idle {
+		data = append(data, "\n[input truncated: pipe idle]"...)
+	}
+	return string(bytes.ToValidUTF8(data, []byte("?"))), idle, nil
+}
+
+// readUntilIdle reads r to EOF, or until no data has arrived for timeout,
+// in which case idle is true. A timeout of 0 waits for EOF. A read still
+// blocked when it gives up is left to the exiting process.
+func readUntilIdle(r io.Reader, timeout time.Duration) (data []byte, idle bool, err error) {
+	if timeout <= 0 {
+		data, err := io.ReadAll(r)
+		return data, false, err
+	}
+
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	chunks := make(chan chunk)
+	go func() {
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := r.Read(buf)
+			chunks <- chunk{buf[:n], err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case c := <-chunks:
+			data = append(data, c.data...)
+			if c.err == io.EOF {
+				return data, false, nil
+			}
+			if c.err != nil {
+				return nil, false, c.err
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			return data, true, nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// slowPipe writes first, pauses, then writes second and closes
+func slowPipe(first, second string, pause time.Duration) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte(first))
+		time.Sleep(pause)
+		w.Write([]byte(second))
+		w.Close()
+	}()
+	return r
+}
+
+func TestReadUntilIdle(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		want     string
+		wantIdle bool
+	}{
+		{"cut short", 20 * time.Millisecond, "building\n", true},
+		{"waits for the pause", time.Second, "building\nerror\n", false},
+		{"no timeout", 0, "building\nerror\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, idle, err := readUntilIdle(slowPipe("building\n", "error\n", 200*time.Millisecond), tt.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want || idle != tt.wantIdle {
+				t.Errorf("read %q (idle %v), want %q (idle %v)", data, idle, tt.want, tt.wantIdle)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	gocontext "context"
+
+	"github.com/geoh/how/internal/context"
+	"github.com/geoh/how/internal/validate"
+)
+
+// validateOptions describes the user's shell to the validator
+func validateOptions(shell context.ShellInfo) validate.Options {
+	var opts validate.Options
+	opts.Shell, opts.ShellPath = userShell(shell)
+	opts.Known = append(append(opts.Known, shell.Aliases...), shell.Functions...)
+	return opts
+}
+
+// checkCommand validates command for the shell described by opts
+func checkCommand(command string, opts validate.Options) []validate.Issue {
+	return validate.Check(gocontext.Background(), command, opts)
+}
+
+// correctCommand asks fix for a correction of a command with issues
+func correctCommand(command string, issues []validate.Issue, opts validate.Options, fix func(problems []string) (string, error)) (string, []validate.Issue, error) {
+	return validate.Correct(gocontext.Background(), command, issues, opts, fix)
+}
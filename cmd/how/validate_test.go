@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/geoh/how/internal/context"
+)
+
+// TestValidateUnknownShell checks that a shell the collector could not
+// detect falls back to $SHELL rather than skipping validation
+func TestValidateUnknownShell(t *testing.T) {
+	t.Setenv("SHELL", "/bin/bash")
+	for _, name := range []string{"", "Unknown"} {
+		opts := validateOptions(context.ShellInfo{Name: name})
+		if opts.Shell != "bash" || opts.ShellPath != "/bin/bash" {
+			t.Errorf("shell %q: validating for %q (%s), want bash", name, opts.Shell, opts.ShellPath)
+		}
+		issues := checkCommand("if true; then ls", opts)
+		if len(issues) == 0 || !issues[0].Syntax {
+			t.Errorf("shell %q: issues %v, want a syntax error", name, issues)
+		}
+	}
+}
@@ -2,11 +2,20 @@ module github.com/geoh/how
 
 go 1.24.7
 
-require golang.design/x/clipboard v0.7.1
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/zalando/go-keyring v0.2.6
+	golang.design/x/clipboard v0.7.1
+	golang.org/x/sys v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
+	mvdan.cc/sh/v3 v3.12.0
+)
 
 require (
+	al.essio.dev/pkg/shellescape v1.5.1 // indirect
+	github.com/danieljoos/wincred v1.2.2 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	golang.org/x/exp/shiny v0.0.0-20250606033433-dcc06ee1d476 // indirect
 	golang.org/x/image v0.28.0 // indirect
 	golang.org/x/mobile v0.0.0-20250606033058-a2a15c67f36f // indirect
-	golang.org/x/sys v0.33.0 // indirect
 )
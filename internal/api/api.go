@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Supported providers
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// Options controls which model is called and how
+type Options struct {
+	Provider string
+	Endpoint string
+	Model    string
+	// Temperature is only sent when set, so that each model otherwise
+	// runs at its own default
+	Temperature *float64
+	Retries     int
+	Timeout     time.Duration
+}
+
+// NeedsAPIKey reports whether requests to provider must be authenticated
+func NeedsAPIKey(provider string) bool {
+	return provider != ProviderOllama
+}
+
+// GenerateResponse sends the prompt to the configured provider and returns
+// the generated text
+func GenerateResponse(apiKey, prompt string, opts Options) (string, error) {
+	switch opts.Provider {
+	case "", ProviderGemini:
+		return generateGemini(apiKey, prompt, opts)
+	case ProviderOpenAI:
+		return generateOpenAI(apiKey, prompt, opts)
+	case ProviderOllama:
+		return generateOllama(prompt, opts)
+	}
+	return "", &ApiError{Message: fmt.Sprintf("Unsupported provider %q", opts.Provider)}
+}
+
+// postWithRetry POSTs reqBody as JSON to url and returns the body of a
+// successful response. Timeouts and rate limits are retried with
+// exponential backoff.
+func postWithRetry(url string, headers map[string]string, reqBody any, opts Options) ([]byte, error) {
+	maxRetries := opts.Retries
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{
+		Timeout: timeout + 5*time.Second,
+	}
+
+	// Marshal request body
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, &ApiError{Message: fmt.Sprintf("Failed to marshal request: %v", err)}
+	}
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		// Create HTTP request
+		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, &ApiError{Message: fmt.Sprintf("Failed to create request: %v", err)}
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		// Make the request
+		resp, err := client.Do(req)
+		if err != nil {
+			if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline exceeded") {
+				if attempt == maxRetries-1 {
+					return nil, &ApiTimeoutError{Message: "API request timed out"}
+				}
+				time.Sleep(time.Duration(1<<uint(attempt)) * time.Second)
+				continue
+			}
+			return nil, &ApiError{Message: fmt.Sprintf("Request failed: %v", err)}
+		}
+
+		// Read response body
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, &ApiError{Message: fmt.Sprintf("Failed to read response: %v", err)}
+		}
+
+		// Check for HTTP errors
+		if resp.StatusCode == 429 {
+			if attempt == maxRetries-1 {
+				return nil, &ApiError{Message: "Rate limit exceeded"}
+			}
+			time.Sleep(time.Duration(1<<uint(attempt)+1) * time.Second)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, &AuthError{Message: fmt.Sprintf("API rejected the key (status %d): %s", resp.StatusCode, string(body))}
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, &ApiError{Message: fmt.Sprintf("API returned status %d: %s", resp.StatusCode, string(body))}
+		}
+
+		return body, nil
+	}
+
+	return nil, &ApiError{Message: "Max retries exceeded"}
+}
+
+// responseText trims generated text and rejects empty responses
+func responseText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", &ContentError{Message: "Empty response from API"}
+	}
+	return text, nil
+}
@@ -1,14 +1,9 @@
 package api
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"strings"
-	"time"
 )
 
 // Error types
@@ -46,7 +41,12 @@ func (e *ApiTimeoutError) Error() string {
 
 // Request and Response structures for Gemini API
 type geminiRequest struct {
-	Contents []content `json:"contents"`
+	Contents         []content        `json:"contents"`
+	GenerationConfig generationConfig `json:"generationConfig,omitzero"`
+}
+
+type generationConfig struct {
+	Temperature *float64 `json:"temperature,omitempty"`
 }
 
 type content struct {
@@ -75,17 +75,24 @@ type promptFeedback struct {
 	BlockReason string `json:"blockReason,omitempty"`
 }
 
-// GenerateResponse generates a response from the Gemini API
-func GenerateResponse(apiKey, prompt string, maxRetries int) (string, error) {
-	modelName := os.Getenv("HOW_MODEL")
+// defaultGeminiEndpoint is the public Gemini API
+const defaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+
+// generateGemini generates a response from the Gemini API
+func generateGemini(apiKey, prompt string, opts Options) (string, error) {
+	modelName := opts.Model
 	if modelName == "" {
 		modelName = "gemini-2.5-flash"
 	}
 
-	// Remove "models/" prefix if present in environment variable
+	// Remove "models/" prefix if present in the configured name
 	modelName = strings.TrimPrefix(modelName, "models/")
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", modelName, apiKey)
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultGeminiEndpoint
+	}
+	url := fmt.Sprintf("%s/models/%s:generateContent", endpoint, modelName)
 
 	// Create request body
 	reqBody := geminiRequest{
@@ -96,91 +103,33 @@ func GenerateResponse(apiKey, prompt string, maxRetries int) (string, error) {
 				},
 			},
 		},
+		GenerationConfig: generationConfig{Temperature: opts.Temperature},
+	}
+
+	body, err := postWithRetry(url, map[string]string{"x-goog-api-key": apiKey}, reqBody, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Parse response
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return "", &ApiError{Message: fmt.Sprintf("Failed to parse response: %v", err)}
+	}
+
+	// Check for blocked content
+	if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		return "", &ContentError{Message: fmt.Sprintf("Blocked: %s", geminiResp.PromptFeedback.BlockReason)}
 	}
 
-	timeout := 30 * time.Second
-	client := &http.Client{
-		Timeout: timeout + 5*time.Second,
+	// Extract text from response
+	if len(geminiResp.Candidates) == 0 {
+		return "", &ContentError{Message: "Empty response from API"}
 	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Marshal request body
-		jsonData, err := json.Marshal(reqBody)
-		if err != nil {
-			return "", &ApiError{Message: fmt.Sprintf("Failed to marshal request: %v", err)}
-		}
-
-		// Create HTTP request
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return "", &ApiError{Message: fmt.Sprintf("Failed to create request: %v", err)}
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-
-		// Make the request
-		resp, err := client.Do(req)
-		if err != nil {
-			if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline exceeded") {
-				if attempt == maxRetries-1 {
-					return "", &ApiTimeoutError{Message: "API request timed out"}
-				}
-				time.Sleep(time.Duration(1<<uint(attempt)) * time.Second)
-				continue
-			}
-			return "", &ApiError{Message: fmt.Sprintf("Request failed: %v", err)}
-		}
-
-		// Read response body
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		if err != nil {
-			return "", &ApiError{Message: fmt.Sprintf("Failed to read response: %v", err)}
-		}
-
-		// Check for HTTP errors
-		if resp.StatusCode == 429 {
-			if attempt == maxRetries-1 {
-				return "", &ApiError{Message: "Rate limit exceeded"}
-			}
-			time.Sleep(time.Duration(1<<uint(attempt)+1) * time.Second)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return "", &ApiError{Message: fmt.Sprintf("API returned status %d: %s", resp.StatusCode, string(body))}
-		}
-
-		// Parse response
-		var geminiResp geminiResponse
-		if err := json.Unmarshal(body, &geminiResp); err != nil {
-			return "", &ApiError{Message: fmt.Sprintf("Failed to parse response: %v", err)}
-		}
-
-		// Check for blocked content
-		if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
-			return "", &ContentError{Message: fmt.Sprintf("Blocked: %s", geminiResp.PromptFeedback.BlockReason)}
-		}
-
-		// Extract text from response
-		if len(geminiResp.Candidates) == 0 {
-			return "", &ContentError{Message: "Empty response from API"}
-		}
-
-		if len(geminiResp.Candidates[0].Content.Parts) == 0 {
-			return "", &ContentError{Message: "No content parts in response"}
-		}
-
-		text := geminiResp.Candidates[0].Content.Parts[0].Text
-		text = strings.TrimSpace(text)
-
-		if text == "" {
-			return "", &ContentError{Message: "Empty response from API"}
-		}
-
-		return text, nil
+	if len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return "", &ContentError{Message: "No content parts in response"}
 	}
 
-	return "", &ApiError{Message: "Max retries exceeded"}
+	return responseText(geminiResp.Candidates[0].Content.Parts[0].Text)
 }
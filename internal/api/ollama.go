@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultOllamaEndpoint is where a local Ollama server listens
+const defaultOllamaEndpoint = "http://localhost:11434"
+
+// Request and Response structures for the Ollama generate API
+type ollamaRequest struct {
+	Model   string        `json:"model"`
+	Prompt  string        `json:"prompt"`
+	Stream  bool          `json:"stream"`
+	Options ollamaOptions `json:"options,omitzero"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type ollamaResponse struct {
+	Response string `json:"response"`
+}
+
+// generateOllama generates a response from an Ollama server
+func generateOllama(prompt string, opts Options) (string, error) {
+	if opts.Model == "" {
+		return "", &ApiError{Message: "A model must be configured for the ollama provider"}
+	}
+
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultOllamaEndpoint
+	}
+
+	reqBody := ollamaRequest{
+		Model:   opts.Model,
+		Prompt:  prompt,
+		Options: ollamaOptions{Temperature: opts.Temperature},
+	}
+
+	body, err := postWithRetry(endpoint+"/api/generate", nil, reqBody, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var ollamaResp ollamaResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return "", &ApiError{Message: fmt.Sprintf("Failed to parse response: %v", err)}
+	}
+
+	return responseText(ollamaResp.Response)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateOllama(t *testing.T) {
+	var got ollamaRequest
+	var raw map[string]any
+	var auth, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, path = r.Header.Get("Authorization"), r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		got, raw = ollamaRequest{}, nil
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		json.Unmarshal(body, &raw)
+		w.Write([]byte(`{"response": "du -sh *\n", "done": true}`))
+	}))
+	defer server.Close()
+
+	// Ollama is unauthenticated, so a key must never be sent to it
+	temperature := 0.7
+	text, err := GenerateResponse("should-not-be-sent", "disk usage", Options{
+		Provider:    ProviderOllama,
+		Endpoint:    server.URL,
+		Model:       "llama3",
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "du -sh *" {
+		t.Errorf("text = %q, want %q", text, "du -sh *")
+	}
+	if path != "/api/generate" {
+		t.Errorf("request went to %s", path)
+	}
+	if auth != "" {
+		t.Errorf("Authorization = %q, want none", auth)
+	}
+	if got.Model != "llama3" || got.Prompt != "disk usage" || got.Stream || got.Options.Temperature == nil || *got.Options.Temperature != temperature {
+		t.Errorf("request body = %+v", got)
+	}
+
+	// Without a configured temperature the model's own default applies
+	if _, err := GenerateResponse("", "disk usage", Options{Provider: ProviderOllama, Endpoint: server.URL, Model: "llama3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["options"]; ok {
+		t.Errorf("request body %v has options", raw)
+	}
+}
+
+func TestGenerateOllamaNoModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("sent a request without a model")
+	}))
+	defer server.Close()
+
+	_, err := GenerateResponse("", "disk usage", Options{Provider: ProviderOllama, Endpoint: server.URL})
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("error %T (%v), want *ApiError", err, err)
+	}
+}
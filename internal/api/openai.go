@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultOpenAIEndpoint is the public OpenAI API. Any OpenAI-compatible
+// gateway can be used by setting the endpoint.
+const defaultOpenAIEndpoint = "https://api.openai.com/v1"
+
+// Request and Response structures for the OpenAI chat completions API
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"`
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason,omitempty"`
+	} `json:"choices"`
+}
+
+// generateOpenAI generates a response from an OpenAI-compatible API
+func generateOpenAI(apiKey, prompt string, opts Options) (string, error) {
+	if opts.Model == "" {
+		return "", &ApiError{Message: "A model must be configured for the openai provider"}
+	}
+
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultOpenAIEndpoint
+	}
+
+	reqBody := openAIRequest{
+		Model: opts.Model,
+		Messages: []openAIMessage{
+			{Role: "user", Content: prompt},
+		},
+		Temperature: opts.Temperature,
+	}
+
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	body, err := postWithRetry(endpoint+"/chat/completions", headers, reqBody, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return "", &ApiError{Message: fmt.Sprintf("Failed to parse response: %v", err)}
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return "", &ContentError{Message: "Empty response from API"}
+	}
+
+	if openAIResp.Choices[0].FinishReason == "content_filter" {
+		return "", &ContentError{Message: "Blocked: content_filter"}
+	}
+
+	return responseText(openAIResp.Choices[0].Message.Content)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateOpenAI(t *testing.T) {
+	var got openAIRequest
+	var raw map[string]any
+	var auth, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, path = r.Header.Get("Authorization"), r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		got, raw = openAIRequest{}, nil
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		json.Unmarshal(body, &raw)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": " ls -la\n"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	text, err := GenerateResponse("sk-test", "list files", Options{
+		Provider:    ProviderOpenAI,
+		Endpoint:    server.URL + "/v1/",
+		Model:       "gpt-4o-mini",
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "ls -la" {
+		t.Errorf("text = %q, want %q", text, "ls -la")
+	}
+	if path != "/v1/chat/completions" {
+		t.Errorf("request went to %s", path)
+	}
+	if auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	want := openAIRequest{Model: "gpt-4o-mini", Messages: []openAIMessage{{Role: "user", Content: "list files"}}, Temperature: &temperature}
+	if got.Model != want.Model || got.Temperature == nil || *got.Temperature != temperature || len(got.Messages) != 1 || got.Messages[0] != want.Messages[0] {
+		t.Errorf("request body = %+v, want %+v", got, want)
+	}
+
+	// Without a configured temperature the model's own default applies
+	if _, err := GenerateResponse("sk-test", "list files", Options{Provider: ProviderOpenAI, Endpoint: server.URL, Model: "gpt-4o-mini"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["temperature"]; ok {
+		t.Errorf("request body %v has a temperature", raw)
+	}
+}
+
+func TestGenerateOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		status int
+		body   string
+		want   error
+	}{
+		{"no model", "", http.StatusOK, "", &ApiError{}},
+		{"rejected key", "gpt-4o-mini", http.StatusUnauthorized, `{"error": "invalid key"}`, &AuthError{}},
+		{"content filter", "gpt-4o-mini", http.StatusOK, `{"choices": [{"message": {"content": ""}, "finish_reason": "content_filter"}]}`, &ContentError{}},
+		{"no choices", "gpt-4o-mini", http.StatusOK, `{"choices": []}`, &ContentError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := GenerateResponse("sk-test", "list files", Options{Provider: ProviderOpenAI, Endpoint: server.URL, Model: tt.model})
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if reflect.TypeOf(err) != reflect.TypeOf(tt.want) {
+				t.Errorf("error %T (%v), want %T", err, err, tt.want)
+			}
+			if tt.model == "" && requests != 0 {
+				t.Errorf("sent %d requests without a model", requests)
+			}
+		})
+	}
+}
@@ -2,11 +2,11 @@ package clipboard
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
 
+	"github.com/geoh/how/internal/platform"
 	"golang.design/x/clipboard"
 )
 
@@ -38,7 +38,7 @@ func copyWithFallback(text string) error {
 
 // copyLinux tries various Linux clipboard methods
 func copyLinux(text string) error {	// In WSL, use Windows clip.exe which copies to Windows clipboard
-	if isWSL() {
+	if platform.IsWSL() {
 		// Try clip.exe from PATH first
 		if err := tryCommand("clip.exe", []string{}, text); err == nil {
 			return nil
@@ -65,7 +65,7 @@ func copyLinux(text string) error {	// In WSL, use Windows clip.exe which copies
 	}
 
 	// If we're in SSH, try OSC 52 escape sequence
-	if isSSH() {
+	if platform.IsSSH() {
 		return copyWithOSC52(text)
 	}
 
@@ -94,25 +94,6 @@ func tryCommand(command string, args []string, text string) error {
 	return cmd.Run()
 }
 
-// isSSH checks if we're running in an SSH session
-func isSSH() bool {
-	return os.Getenv("SSH_CLIENT") != "" || os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
-}
-
-// isWSL checks if we're running in Windows Subsystem for Linux
-func isWSL() bool {
-	// Check for WSL-specific environment variable
-	if os.Getenv("WSL_DISTRO_NAME") != "" {
-		return true
-	}
-	// Check /proc/version for Microsoft/WSL indicators
-	if data, err := os.ReadFile("/proc/version"); err == nil {
-		version := strings.ToLower(string(data))
-		return strings.Contains(version, "microsoft") || strings.Contains(version, "wsl")
-	}
-	return false
-}
-
 // copyWithOSC52 uses OSC 52 escape sequence for SSH clipboard
 func copyWithOSC52(text string) error {
 	// OSC 52 is supported by many modern terminals including Windows Terminal
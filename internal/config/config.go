@@ -7,53 +7,76 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
-)
 
-var (
-	configDir   string
-	apiKeyFile  string
-	historyFile string
+	"github.com/geoh/how/internal/platform"
 )
 
-func init() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = "."
-	}
-	configDir = filepath.Join(homeDir, ".how-cli")
-	apiKeyFile = filepath.Join(configDir, ".google_api_key")
-	historyFile = filepath.Join(configDir, "history.log")
-}
-
-// GetOrCreateAPIKey retrieves the API key from environment or file, or prompts for it
-func GetOrCreateAPIKey(forceReenter bool) (string, error) {
+// GetOrCreateAPIKey retrieves the API key from the environment, the
+// configured command, the keyring or the key file, or prompts for it
+func GetOrCreateAPIKey(cfg *Config, forceReenter bool) (string, error) {
 	var apiKey string
 
+	envName := apiKeyEnv(cfg)
+	keyPath := keyFile(cfg)
+
 	if !forceReenter {
 		// Check environment variable first
-		apiKey = os.Getenv("GOOGLE_API_KEY")
+		if envName != "" {
+			apiKey = os.Getenv(envName)
+		}
 
-		// If not in environment, check the file
-		if apiKey == "" {
-			data, err := os.ReadFile(apiKeyFile)
+		// Then an external secret command such as `pass show gemini`
+		if apiKey == "" && cfg.APIKeyCommand != "" {
+			key, err := runSecretCommand(cfg.APIKeyCommand)
+			if err != nil {
+				return "", fmt.Errorf("api_key_command failed: %v", err)
+			}
+			apiKey = key
+		}
+
+		// Then the OS keyring
+		if apiKey == "" && cfg.APIKeyStore == StoreKeyring {
+			key, err := keyringGet(cfg)
+			if err != nil && cfg.Debug {
+				fmt.Fprintf(os.Stderr, "Warning: Could not read keyring: %v\n", err)
+			}
+			apiKey = key
+		}
+
+		// If not found yet, check the file
+		if apiKey == "" && keyPath != "" {
+			data, err := os.ReadFile(keyPath)
 			if err == nil {
 				apiKey = strings.TrimSpace(string(data))
+
+				// Move keys left in plaintext into the keyring when possible
+				if apiKey != "" && cfg.APIKeyStore == StoreKeyring && keyringSet(cfg, apiKey) == nil {
+					os.Remove(keyPath)
+				}
 			}
 		}
 	}
 
 	// If still no API key or force re-enter, prompt the user
 	if apiKey == "" || forceReenter {
-		// Check if stdin is a terminal
-		fileInfo, _ := os.Stdin.Stat()
-		if (fileInfo.Mode() & os.ModeCharDevice) == 0 {
-			return "", fmt.Errorf("GOOGLE_API_KEY not found in non-interactive session")
+		// Read from the terminal even when stdin is piped input
+		tty, err := platform.OpenTerminal()
+		if err != nil {
+			if envName == "" {
+				return "", fmt.Errorf("no API key for provider %s found in non-interactive session", cfg.Provider)
+			}
+			return "", fmt.Errorf("%s not found in non-interactive session", envName)
 		}
+		defer tty.Close()
 
-		fmt.Println("Paste your Google Gemini API key:")
+		if cfg.Profile != "" {
+			fmt.Printf("Paste the API key for profile %s:\n", cfg.Profile)
+		} else {
+			fmt.Printf("Paste your %s API key:\n", providerName(cfg))
+		}
 		fmt.Print("API Key: ")
 
-		reader := bufio.NewReader(os.Stdin)
+		reader := bufio.NewReader(tty)
 		input, err := reader.ReadString('\n')
 		if err != nil {
 			return "", fmt.Errorf("API key input cancelled")
@@ -65,7 +88,7 @@ func GetOrCreateAPIKey(forceReenter bool) (string, error) {
 		}
 
 		// Save the API key
-		if err := SaveAPIKey(apiKey); err != nil {
+		if err := SaveAPIKey(cfg, apiKey); err != nil {
 			// Log warning but continue
 			fmt.Fprintf(os.Stderr, "Warning: Could not save API key: %v\n", err)
 		}
@@ -74,56 +97,151 @@ func GetOrCreateAPIKey(forceReenter bool) (string, error) {
 	return apiKey, nil
 }
 
-// SaveAPIKey saves the API key to the config file
-func SaveAPIKey(apiKey string) error {
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+// apiKeyEnv returns the environment variable holding the API key, or ""
+// for none. GOOGLE_API_KEY is only a default for Gemini's public API; other
+// providers and endpoints must be given api_key_env, so the Gemini key is
+// never sent elsewhere.
+func apiKeyEnv(cfg *Config) string {
+	if cfg.APIKeyEnv == "" && isGemini(cfg) && cfg.Endpoint == "" {
+		return "GOOGLE_API_KEY"
+	}
+	return cfg.APIKeyEnv
+}
+
+// SaveAPIKey stores the API key in the configured store. Plaintext file
+// storage is only used when api_key_store is set to "file".
+func SaveAPIKey(cfg *Config, apiKey string) error {
+	keyPath := keyFile(cfg)
+
+	if cfg.APIKeyStore == StoreKeyring {
+		if err := keyringSet(cfg, apiKey); err != nil {
+			return fmt.Errorf("%v (run `how config set api_key_store file` to store it in plaintext instead)", err)
+		}
+		// Drop any plaintext copy left from before
+		if keyPath != "" {
+			os.Remove(keyPath)
+		}
+		return nil
+	}
+
+	if errNoHome != nil {
+		return errNoHome
+	}
+
+	// Create data directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
 		return err
 	}
 
 	// Write the API key to the file
-	if err := os.WriteFile(apiKeyFile, []byte(apiKey), 0600); err != nil {
+	if err := os.WriteFile(keyPath, []byte(apiKey), 0600); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// LogHistory appends a question and commands to the history file
-func LogHistory(question string, commands []string) error {
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+// HistoryEntry is a single question and the commands generated for it
+type HistoryEntry struct {
+	Question string
+	Commands []string
+	// Profile is the profile that was active, if any
+	Profile string
+	// ExitCode is set when the commands were run with --run
+	ExitCode *int
+}
+
+// LogHistory appends an entry to the history file, keeping at most
+// maxEntries entries (0 keeps everything). Concurrent invocations are
+// serialised with an advisory lock and each entry is written in one call,
+// so entries never interleave.
+func LogHistory(entry HistoryEntry, maxEntries int) error {
+	if errNoHome != nil {
+		return errNoHome
+	}
+
+	// Create state directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0755); err != nil {
 		return err
 	}
 
+	// Format the whole entry up front
+	var b strings.Builder
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	fmt.Fprintf(&b, "[%s] Q: %s\n", timestamp, entry.Question)
+	if entry.Profile != "" {
+		fmt.Fprintf(&b, "Profile: %s\n", entry.Profile)
+	}
+	b.WriteString("Commands:\n")
+	for _, cmd := range entry.Commands {
+		fmt.Fprintf(&b, "%s\n", cmd)
+	}
+	if entry.ExitCode != nil {
+		fmt.Fprintf(&b, "Exit code: %d\n", *entry.ExitCode)
+	}
+	b.WriteString("\n")
+
+	// The lock lives in its own file because trimming replaces the log
+	unlock, err := lockFile(historyFile + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Open file in append mode
 	f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	// Write the log entry
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	if _, err := fmt.Fprintf(f, "[%s] Q: %s\nCommands:\n", timestamp, question); err != nil {
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
 		return err
 	}
 
-	for _, cmd := range commands {
-		if _, err := fmt.Fprintf(f, "%s\n", cmd); err != nil {
-			return err
-		}
+	if err := f.Close(); err != nil {
+		return err
 	}
 
-	if _, err := fmt.Fprintln(f); err != nil {
-		return err
+	if maxEntries > 0 {
+		return trimHistory(maxEntries)
 	}
 
 	return nil
 }
 
+// trimHistory drops the oldest entries so that at most maxEntries remain.
+// The caller must hold the history lock.
+func trimHistory(maxEntries int) error {
+	data, err := os.ReadFile(historyFile)
+	if err != nil {
+		return err
+	}
+
+	// Entries are separated by a blank line
+	entries := strings.SplitAfter(string(data), "\n\n")
+	if last := entries[len(entries)-1]; last == "" {
+		entries = entries[:len(entries)-1]
+	}
+	if len(entries) <= maxEntries {
+		return nil
+	}
+
+	// Replace the file in one step so readers never see a partial log
+	kept := strings.Join(entries[len(entries)-maxEntries:], "")
+	tmp := historyFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(kept), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, historyFile)
+}
+
 // ShowHistory displays the history file contents
 func ShowHistory() error {
+	if errNoHome != nil {
+		return errNoHome
+	}
+
 	data, err := os.ReadFile(historyFile)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Environment that turns the test binary into one of the history writers
+const (
+	writerEnv     = "HOW_TEST_HISTORY_WRITER"
+	writerMaxEnv  = "HOW_TEST_HISTORY_MAX"
+	writerEntries = 50
+	writers       = 8
+)
+
+func TestMain(m *testing.M) {
+	if id := os.Getenv(writerEnv); id != "" {
+		os.Exit(writeHistory(id))
+	}
+	os.Exit(m.Run())
+}
+
+// writeHistory logs writerEntries entries as writer id. Each entry carries
+// a long command so that unserialised writes would split it.
+func writeHistory(id string) int {
+	maxEntries, _ := strconv.Atoi(os.Getenv(writerMaxEnv))
+	for i := 0; i < writerEntries; i++ {
+		entry := HistoryEntry{
+			Question: fmt.Sprintf("writer %s entry %d", id, i),
+			Commands: []string{strings.Repeat(id, 4096)},
+		}
+		if err := LogHistory(entry, maxEntries); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+func TestLogHistoryConcurrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		want       int
+	}{
+		{"append", 0, writers * writerEntries},
+		{"trim", writerEntries, writerEntries},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			exe, err := os.Executable()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmds := make([]*exec.Cmd, writers)
+			for i := range cmds {
+				cmd := exec.Command(exe, "-test.run=^$")
+				cmd.Env = append(os.Environ(),
+					"HOW_HOME="+home,
+					writerEnv+"="+string(rune('a'+i)),
+					writerMaxEnv+"="+strconv.Itoa(tt.maxEntries),
+				)
+				cmd.Stderr = os.Stderr
+				if err := cmd.Start(); err != nil {
+					t.Fatal(err)
+				}
+				cmds[i] = cmd
+			}
+			for _, cmd := range cmds {
+				if err := cmd.Wait(); err != nil {
+					t.Fatalf("writer failed: %v", err)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(home, "history.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries := strings.SplitAfter(string(data), "\n\n")
+			if entries[len(entries)-1] == "" {
+				entries = entries[:len(entries)-1]
+			}
+			if len(entries) != tt.want {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.want)
+			}
+
+			seen := map[string]bool{}
+			for _, entry := range entries {
+				question, id, ok := parseEntry(entry)
+				if !ok {
+					t.Fatalf("corrupt entry:\n%.200s", entry)
+				}
+				if seen[question] {
+					t.Fatalf("duplicate entry %q", question)
+				}
+				seen[question] = true
+				if want := strings.Repeat(id, 4096); !strings.Contains(entry, "\n"+want+"\n") {
+					t.Fatalf("entry %q has a mangled command", question)
+				}
+			}
+		})
+	}
+}
+
+// parseEntry returns the question of a history entry and the writer that
+// logged it
+func parseEntry(entry string) (question, id string, ok bool) {
+	first, rest, ok := strings.Cut(entry, "\n")
+	if !ok || !strings.HasPrefix(rest, "Commands:\n") || !strings.HasSuffix(rest, "\n\n") {
+		return "", "", false
+	}
+	_, question, ok = strings.Cut(first, "] Q: ")
+	if !ok {
+		return "", "", false
+	}
+	var n int
+	if _, err := fmt.Sscanf(question, "writer %s entry %d", &id, &n); err != nil {
+		return "", "", false
+	}
+	return question, id, true
+}
+
+func TestKeyPerProvider(t *testing.T) {
+	old := apiKeyFile
+	apiKeyFile = filepath.Join("data", ".google_api_key")
+	t.Cleanup(func() { apiKeyFile = old })
+
+	tests := []struct {
+		cfg         Config
+		wantAccount string
+		wantFile    string
+	}{
+		{Config{Provider: "gemini"}, "google_api_key", filepath.Join("data", ".google_api_key")},
+		{Config{}, "google_api_key", filepath.Join("data", ".google_api_key")},
+		{Config{Provider: "openai", Endpoint: "https://gw.example.com/v1"}, "openai_api_key", filepath.Join("data", ".openai_api_key")},
+		{Config{Provider: "openai", Profile: "work"}, "profile:work", filepath.Join("data", ".google_api_key.work")},
+		{Config{Provider: "gemini", Profile: "home"}, "profile:home", filepath.Join("data", ".google_api_key.home")},
+	}
+	for _, tt := range tests {
+		if got := keyringAccount(&tt.cfg); got != tt.wantAccount {
+			t.Errorf("keyringAccount(%+v) = %q, want %q", tt.cfg, got, tt.wantAccount)
+		}
+		if got := keyFile(&tt.cfg); got != tt.wantFile {
+			t.Errorf("keyFile(%+v) = %q, want %q", tt.cfg, got, tt.wantFile)
+		}
+	}
+}
+
+func TestAPIKeyEnv(t *testing.T) {
+	tests := []struct {
+		provider, apiKeyEnv string
+		want                string
+	}{
+		{"gemini", "", "GOOGLE_API_KEY"},
+		{"", "", "GOOGLE_API_KEY"},
+		{"gemini", "MY_GEMINI_KEY", "MY_GEMINI_KEY"},
+		{"openai", "", ""},
+		{"openai", "CORP_LLM_KEY", "CORP_LLM_KEY"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Provider, cfg.APIKeyEnv = tt.provider, tt.apiKeyEnv
+		if got := apiKeyEnv(cfg); got != tt.want {
+			t.Errorf("apiKeyEnv(provider %q, api_key_env %q) = %q, want %q", tt.provider, tt.apiKeyEnv, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyEnvCustomEndpoint(t *testing.T) {
+	cfg := Default()
+	cfg.Endpoint = "https://llm-gateway.example.com/v1beta"
+	if got := apiKeyEnv(cfg); got != "" {
+		t.Errorf("apiKeyEnv for Gemini at %s = %q, want none", cfg.Endpoint, got)
+	}
+}
+
+func TestApplyProfileKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		// keep is true when the top-level key settings still apply
+		keep bool
+	}{
+		{"model only", Profile{Model: "gemini-2.5-pro"}, true},
+		{"same provider", Profile{Provider: "gemini"}, true},
+		{"other provider", Profile{Provider: "openai", Model: "gpt-4o"}, false},
+		{"other endpoint", Profile{Endpoint: "https://llm-gateway.example.com/v1beta"}, false},
+		{"own key", Profile{Endpoint: "https://llm-gateway.example.com/v1beta", APIKeyEnv: "CORP_KEY"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.APIKeyEnv = "MY_GEMINI_KEY"
+			cfg.APIKeyCommand = "pass show gemini"
+			cfg.Profiles = map[string]Profile{"p": tt.profile}
+			if err := cfg.applyProfile("p"); err != nil {
+				t.Fatal(err)
+			}
+
+			wantEnv, wantCommand := tt.profile.APIKeyEnv, tt.profile.APIKeyCommand
+			if tt.keep {
+				wantEnv, wantCommand = "MY_GEMINI_KEY", "pass show gemini"
+			}
+			if cfg.APIKeyEnv != wantEnv || cfg.APIKeyCommand != wantCommand {
+				t.Errorf("api_key_env %q, api_key_command %q; want %q, %q", cfg.APIKeyEnv, cfg.APIKeyCommand, wantEnv, wantCommand)
+			}
+		})
+	}
+}
+
+func TestProfileProvider(t *testing.T) {
+	cfg := Default()
+	cfg.Profiles = map[string]Profile{
+		"local": {Provider: "ollama", Model: "llama3"},
+		"pro":   {Model: "gemini-2.5-pro"},
+	}
+	if err := cfg.applyProfile("local"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"local": "ollama", "pro": "gemini"} {
+		if got := cfg.ProfileProvider(name); got != want {
+			t.Errorf("ProfileProvider(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEditConfig(t *testing.T) {
+	const file = `# how settings
+model = "gemini-2.5-flash" # fast enough
+
+[context]
+# collectors to add
+enabled = ["shell_history"]
+timeout = "2s"
+
+[history]
+enabled = true
+`
+	tests := []struct {
+		name  string
+		in    string
+		key   string
+		value any
+		want  string
+	}{
+		{"replace top-level", file, "model", "gemini-2.5-pro",
+			strings.Replace(file, `model = "gemini-2.5-flash" # fast enough`, `model = "gemini-2.5-pro"`, 1)},
+		{"replace in table", file, "context.timeout", "3s",
+			strings.Replace(file, `timeout = "2s"`, `timeout = "3s"`, 1)},
+		{"add to table", file, "history.max_entries", int64(500),
+			strings.Replace(file, "enabled = true\n", "enabled = true\nmax_entries = 500\n", 1)},
+		{"add table", file, "stdin.timeout", "10s", file + "\n[stdin]\ntimeout = \"10s\"\n"},
+		{"add top-level", file, "profile", "work",
+			strings.Replace(file, "fast enough\n", "fast enough\nprofile = \"work\"\n", 1)},
+		{"add top-level before first table", "[history]\nenabled = true\n", "profile", "work",
+			"profile = \"work\"\n\n[history]\nenabled = true\n"},
+		{"empty file", "", "typewriter.enabled", true, "[typewriter]\nenabled = true\n"},
+		{"dotted key", "context.timeout = \"2s\"\n", "context.timeout", "5s", "context.timeout = \"5s\"\n"},
+		{"skip multi-line string", "prompt = \"\"\"\n[context]\ntimeout = 1\n\"\"\"\n\n[context]\ntimeout = \"2s\"\n", "context.timeout", "4s",
+			"prompt = \"\"\"\n[context]\ntimeout = 1\n\"\"\"\n\n[context]\ntimeout = \"4s\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editConfig([]byte(tt.in), tt.key, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEditConfigFallback checks that a value spanning several lines is
+// still replaced, by encoding the file again
+func TestEditConfigFallback(t *testing.T) {
+	in := "[context]\nenabled = [\n  \"shell_history\",\n]\n"
+	got, err := editConfig([]byte(in), "context.enabled", []string{"last_command"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if _, err := toml.Decode(string(got), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"last_command"}; !reflect.DeepEqual(cfg.Context.Enabled, want) {
+		t.Errorf("context.enabled = %v, want %v in:\n%s", cfg.Context.Enabled, want, got)
+	}
+
+	if _, err := editConfig([]byte("model = \n"), "model", "x"); err == nil {
+		t.Error("no error for an invalid file")
+	}
+}
+
+func TestSaveSettingKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	old := userConfigFile
+	userConfigFile = filepath.Join(dir, "config.toml")
+	t.Cleanup(func() { userConfigFile = old })
+
+	// Dotfile managers link the config from elsewhere
+	target := filepath.Join(dir, "dotfiles.toml")
+	if err := os.WriteFile(target, []byte("# mine\nmodel = \"a\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, userConfigFile); err != nil {
+		t.Skip(err)
+	}
+
+	if err := SaveSetting("model", "b"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# mine\nmodel = \"b\"\n"; string(data) != want {
+		t.Errorf("config is %q, want %q", data, want)
+	}
+	if info, err := os.Lstat(userConfigFile); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("config is no longer a symlink")
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode changed: %v", info.Mode())
+	}
+
+	// An invalid value leaves the file alone
+	if err := SaveSetting("typewriter.enabled", "maybe"); err == nil {
+		t.Error("no error for an invalid value")
+	}
+	if after, _ := os.ReadFile(target); string(after) != string(data) {
+		t.Errorf("config changed to %q", after)
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	for _, s := range []string{"true", "1", "yes", "Y", "on", "ON"} {
+		if b, err := parseBool(s); err != nil || !b {
+			t.Errorf("parseBool(%q) = %v, %v; want true", s, b, err)
+		}
+	}
+	for _, s := range []string{"false", "0", "no", "off"} {
+		if b, err := parseBool(s); err != nil || b {
+			t.Errorf("parseBool(%q) = %v, %v; want false", s, b, err)
+		}
+	}
+	if _, err := parseBool("maybe"); err == nil {
+		t.Error("parseBool accepted maybe")
+	}
+}
+
+// TestSecretCommandStdin checks that api_key_command never reads what was
+// piped to how
+func TestSecretCommandStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	old := openTerminal
+	openTerminal = func() (*os.File, error) { return nil, os.ErrNotExist }
+	t.Cleanup(func() { openTerminal = old })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("piped question\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin; r.Close() })
+
+	key, err := runSecretCommand("cat; echo sk-from-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "sk-from-manager" {
+		t.Errorf("key = %q: the command read piped stdin", key)
+	}
+}
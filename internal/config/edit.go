@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var (
+	// tableRe matches a [table] header, but not an [[array]] one
+	tableRe = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*(?:#.*)?$`)
+	// keyRe matches a key = value line, with the key possibly dotted
+	keyRe = regexp.MustCompile(`^(\s*)((?:[A-Za-z0-9_\-]+|"[^"]*"|'[^']*')(?:\s*\.\s*(?:[A-Za-z0-9_\-]+|"[^"]*"|'[^']*'))*)\s*=\s*(.*)$`)
+	// keyPartRe matches one part of a dotted key
+	keyPartRe = regexp.MustCompile(`[A-Za-z0-9_\-]+|"[^"]*"|'[^']*'`)
+	bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+)
+
+// editConfig returns the config file raw with key set to value. Only the
+// line holding the key is rewritten, or a line added, so that comments and
+// layout are kept. If that does not give exactly the intended change, for
+// example because the old value spans several lines, the whole file is
+// encoded again instead.
+func editConfig(raw []byte, key string, value any) ([]byte, error) {
+	data := map[string]any{}
+	if _, err := toml.Decode(string(raw), &data); err != nil {
+		return nil, err
+	}
+
+	var encoded bytes.Buffer
+	if err := toml.NewEncoder(&encoded).Encode(map[string]any{"v": value}); err != nil {
+		return nil, err
+	}
+	_, literal, _ := strings.Cut(strings.TrimSpace(encoded.String()), " = ")
+
+	// What the file should decode to afterwards
+	var decoded map[string]any
+	if _, err := toml.Decode("v = "+literal, &decoded); err != nil {
+		return nil, err
+	}
+	parts := strings.Split(key, ".")
+	table := data
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			table[part] = next
+		}
+		table = next
+	}
+	table[parts[len(parts)-1]] = decoded["v"]
+
+	if edited, ok := setKeyLine(string(raw), parts, literal); ok {
+		got := map[string]any{}
+		if _, err := toml.Decode(edited, &got); err == nil && reflect.DeepEqual(got, data) {
+			return []byte(edited), nil
+		}
+	}
+
+	var b bytes.Buffer
+	if err := toml.NewEncoder(&b).Encode(data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// setKeyLine sets the key made of parts to literal in the TOML text,
+// replacing the line that holds it or adding one to its table. It gives up
+// on values it can't replace line by line.
+func setKeyLine(text string, parts []string, literal string) (string, bool) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+	want := strings.Join(parts, ".")
+	wantTable := strings.Join(parts[:len(parts)-1], ".")
+	leaf := parts[len(parts)-1]
+	if !bareKeyRe.MatchString(leaf) {
+		leaf = fmt.Sprintf("%q", leaf)
+	}
+
+	table := ""
+	// Where a new line for the key goes: after the last line of its table
+	insertAt, firstHeader := -1, -1
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(strings.TrimSpace(line), "[[") {
+			table = "[["
+			if firstHeader == -1 {
+				firstHeader = i
+			}
+			continue
+		}
+		if m := tableRe.FindStringSubmatch(line); m != nil {
+			table = normalizeKey(m[1])
+			if firstHeader == -1 {
+				firstHeader = i
+			}
+			if table == wantTable {
+				insertAt = i + 1
+			}
+			continue
+		}
+
+		m := keyRe.FindStringSubmatch(line)
+		if m == nil {
+			if table == wantTable && strings.TrimSpace(line) != "" && insertAt != -1 {
+				insertAt = i + 1
+			}
+			continue
+		}
+		full := normalizeKey(m[2])
+		if table != "" {
+			full = table + "." + full
+		}
+		if multiLine(m[3]) {
+			if full == want {
+				return "", false
+			}
+			// Skip the rest of the value; a line in it may look like a key
+			for i+1 < len(lines) && !closesMultiLine(m[3], lines[i+1]) {
+				i++
+			}
+			i++
+			continue
+		}
+		if full == want {
+			lines[i] = m[1] + m[2] + " = " + literal
+			return strings.Join(lines, "\n") + "\n", true
+		}
+		if table == wantTable {
+			insertAt = i + 1
+		}
+	}
+
+	line := leaf + " = " + literal
+	switch {
+	case wantTable == "" && firstHeader != -1:
+		// Top-level keys must come before the first table
+		at := firstHeader
+		for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = insertLine(lines, at, line)
+		if at == firstHeader {
+			lines = insertLine(lines, at+1, "")
+		}
+	case wantTable == "":
+		lines = append(lines, line)
+	case insertAt != -1:
+		lines = insertLine(lines, insertAt, line)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+wantTable+"]", line)
+	}
+	return strings.Join(lines, "\n") + "\n", true
+}
+
+// normalizeKey turns a possibly quoted, dotted key into plain parts joined
+// by dots
+func normalizeKey(key string) string {
+	var parts []string
+	for _, part := range keyPartRe.FindAllString(key, -1) {
+		parts = append(parts, strings.Trim(part, `"'`))
+	}
+	return strings.Join(parts, ".")
+}
+
+// multiLine reports whether a value continues on the following lines
+func multiLine(value string) bool {
+	value = strings.TrimSpace(value)
+	for _, quote := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(value, quote) {
+			return !strings.Contains(value[len(quote):], quote)
+		}
+	}
+	return strings.HasPrefix(value, "[") && strings.Count(value, "[") > strings.Count(value, "]")
+}
+
+// closesMultiLine reports whether line ends the value started by first
+func closesMultiLine(first, line string) bool {
+	first = strings.TrimSpace(first)
+	for _, quote := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(first, quote) {
+			return strings.Contains(line, quote)
+		}
+	}
+	return strings.HasPrefix(strings.TrimSpace(line), "]")
+}
+
+// insertLine inserts line into lines before index at
+func insertLine(lines []string, at int, line string) []string {
+	lines = append(lines, "")
+	copy(lines[at+1:], lines[at:])
+	lines[at] = line
+	return lines
+}
+
+// writeFileAtomic replaces the file at path with data through a temporary
+// file in the same directory, so a failed write leaves the old file intact.
+// A symlinked file, as dotfile managers create, keeps its link.
+func writeFileAtomic(path string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+package config
+
+import "os"
+
+// lockFile takes an exclusive advisory lock on path, creating the file if
+// needed, and blocks until the lock is acquired. The returned function
+// releases it.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
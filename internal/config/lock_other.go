@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package config
+
+import "os"
+
+// lock is a no-op on platforms without advisory file locks
+func lock(f *os.File) error {
+	return nil
+}
+
+// unlock is a no-op on platforms without advisory file locks
+func unlock(f *os.File) error {
+	return nil
+}
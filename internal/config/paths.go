@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	configDir string
+	dataDir   string
+	stateDir  string
+	cacheDir  string
+
+	apiKeyFile     string
+	historyFile    string
+	userConfigFile string
+
+	// legacyDir is where versions before XDG support kept their files
+	legacyDir string
+
+	// errNoHome is set when no directory could be resolved for our files
+	errNoHome error
+)
+
+func init() {
+	resolvePaths()
+}
+
+// resolvePaths works out where config, data, state and cache files live.
+// HOW_HOME puts everything under a single directory; otherwise the XDG
+// base directory variables are honoured, falling back to their defaults
+// under the home directory.
+func resolvePaths() {
+	homeDir, homeErr := os.UserHomeDir()
+	if homeErr == nil {
+		legacyDir = filepath.Join(homeDir, ".how-cli")
+	}
+
+	if howHome := os.Getenv("HOW_HOME"); howHome != "" {
+		configDir = howHome
+		dataDir = howHome
+		stateDir = howHome
+		cacheDir = filepath.Join(howHome, "cache")
+	} else {
+		configDir = xdgDir("XDG_CONFIG_HOME", homeDir, ".config")
+		dataDir = xdgDir("XDG_DATA_HOME", homeDir, ".local", "share")
+		stateDir = xdgDir("XDG_STATE_HOME", homeDir, ".local", "state")
+		cacheDir = xdgDir("XDG_CACHE_HOME", homeDir, ".cache")
+	}
+
+	// Never fall back to the current directory: that could drop the API
+	// key file into whatever repository the user happens to be in
+	if configDir == "" || dataDir == "" || stateDir == "" || cacheDir == "" {
+		errNoHome = fmt.Errorf("cannot determine home directory; set HOW_HOME or the XDG_*_HOME variables")
+	}
+
+	userConfigFile = joinIfSet(configDir, "config.toml")
+	apiKeyFile = joinIfSet(dataDir, ".google_api_key")
+	historyFile = joinIfSet(stateDir, "history.log")
+}
+
+// xdgDir returns $env/how when env is set to an absolute path, and
+// home/fallback.../how otherwise
+func xdgDir(env, homeDir string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "how")
+	}
+	if homeDir == "" {
+		return ""
+	}
+	return filepath.Join(append(append([]string{homeDir}, fallback...), "how")...)
+}
+
+// joinIfSet joins name onto dir, keeping the result empty when dir is
+func joinIfSet(dir, name string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
+
+// CacheDir returns the directory for disposable cached data
+func CacheDir() (string, error) {
+	if cacheDir == "" {
+		return "", errNoHome
+	}
+	return cacheDir, nil
+}
+
+// MigrateLegacy moves files from the pre-XDG ~/.how-cli directory to their
+// new locations. Files that already exist at the destination are left
+// alone. It returns true if anything was moved.
+func MigrateLegacy() (bool, error) {
+	if legacyDir == "" || errNoHome != nil {
+		return false, nil
+	}
+	if _, err := os.Stat(legacyDir); err != nil {
+		return false, nil
+	}
+
+	moves := map[string]string{
+		filepath.Join(legacyDir, ".google_api_key"): apiKeyFile,
+		filepath.Join(legacyDir, "history.log"):     historyFile,
+	}
+
+	moved := false
+	for from, to := range moves {
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			continue
+		}
+		if err := moveFile(from, to); err != nil {
+			return moved, fmt.Errorf("error migrating %s: %v", from, err)
+		}
+		moved = true
+	}
+
+	// Only succeeds once the directory is empty
+	os.Remove(legacyDir)
+
+	return moved, nil
+}
+
+// moveFile renames from to to, copying when they are on different devices
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(from)
+}
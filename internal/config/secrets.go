@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/geoh/how/internal/platform"
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring entry holding the Gemini API key. The keyring is the Secret
+// Service over D-Bus on Linux, the Keychain on macOS and the Credential
+// Manager on Windows. Other providers and profiles get their own entries,
+// so a key is only ever sent to the provider it was entered for.
+const (
+	keyringService = "how"
+	keyringUser    = "google_api_key"
+)
+
+// secretCommandTimeout bounds api_key_command, which may wait on a
+// password manager prompt
+const secretCommandTimeout = time.Minute
+
+// openTerminal opens the terminal api_key_command reads from; tests
+// replace it
+var openTerminal = platform.OpenTerminal
+
+// isGemini reports whether cfg talks to Gemini, the default provider
+func isGemini(cfg *Config) bool {
+	return cfg.Provider == "" || cfg.Provider == "gemini"
+}
+
+// keyringAccount returns the keyring entry for the active profile or
+// provider
+func keyringAccount(cfg *Config) string {
+	switch {
+	case cfg.Profile != "":
+		return "profile:" + cfg.Profile
+	case isGemini(cfg):
+		return keyringUser
+	default:
+		return cfg.Provider + "_api_key"
+	}
+}
+
+// keyFile returns the plaintext key file for the active profile or
+// provider
+func keyFile(cfg *Config) string {
+	switch {
+	case apiKeyFile == "" || isGemini(cfg) && cfg.Profile == "":
+		return apiKeyFile
+	case cfg.Profile != "":
+		return apiKeyFile + "." + cfg.Profile
+	default:
+		return filepath.Join(filepath.Dir(apiKeyFile), "."+cfg.Provider+"_api_key")
+	}
+}
+
+// providerName describes the provider cfg sends requests to, for prompts
+func providerName(cfg *Config) string {
+	switch {
+	case isGemini(cfg):
+		return "Google Gemini"
+	case cfg.Endpoint != "":
+		return cfg.Provider + " (" + cfg.Endpoint + ")"
+	default:
+		return cfg.Provider
+	}
+}
+
+// keyringGet reads the API key from the OS keyring, returning "" if no key
+// is stored
+func keyringGet(cfg *Config) (string, error) {
+	key, err := keyring.Get(keyringService, keyringAccount(cfg))
+	if err == keyring.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(key), nil
+}
+
+// keyringSet stores the API key in the OS keyring
+func keyringSet(cfg *Config, apiKey string) error {
+	if err := keyring.Set(keyringService, keyringAccount(cfg), apiKey); err != nil {
+		return fmt.Errorf("could not store API key in the OS keyring: %v", err)
+	}
+	return nil
+}
+
+// runSecretCommand runs command through the user's shell and returns its
+// trimmed output
+func runSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	// Let password managers prompt on the terminal. Stdin may be input
+	// piped to how, which is not theirs to read; without a terminal they
+	// read nothing.
+	if tty, err := openTerminal(); err == nil {
+		defer tty.Close()
+		cmd.Stdin = tty
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	// Tools like `pass` print extra lines after the secret
+	key, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("command printed nothing")
+	}
+	return key, nil
+}
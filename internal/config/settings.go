@@ -0,0 +1,716 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Sources a setting can come from, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceUser    = "user"
+	SourceProject = "project"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// API key stores
+const (
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+)
+
+// ProjectConfigName is the file name of the per-project config file
+const ProjectConfigName = ".how.toml"
+
+// Config holds all user-tunable settings. Each field is addressed by its
+// dotted toml key (e.g. "history.enabled") and can be overridden with the
+// matching HOW_<KEY> environment variable (e.g. HOW_HISTORY_ENABLED).
+type Config struct {
+	// Profile names the entry of Profiles applied on top of the files
+	Profile string `toml:"profile"`
+
+	Provider    string        `toml:"provider"`
+	Endpoint    string        `toml:"endpoint"`
+	Model       string        `toml:"model"`
+	Temperature float64       `toml:"temperature"`
+	Retries     int           `toml:"retries"`
+	Timeout     time.Duration `toml:"timeout"`
+	Debug       bool          `toml:"debug"`
+
+	// APIKeyEnv names the environment variable holding the API key
+	APIKeyEnv string `toml:"api_key_env"`
+	// APIKeyCommand is run through the shell to print the API key
+	APIKeyCommand string `toml:"api_key_command"`
+	// APIKeyStore is where a prompted or --api-key key is saved
+	APIKeyStore string `toml:"api_key_store"`
+
+	// Prompt holds extra instructions added to every prompt
+	Prompt string `toml:"prompt"`
+	// Facts are short statements about the project or machine, e.g.
+	// "we use pnpm, not npm", passed to the model as context
+	Facts []string `toml:"facts"`
+
+	Clipboard  ClipboardConfig  `toml:"clipboard"`
+	Typewriter TypewriterConfig `toml:"typewriter"`
+	History    HistoryConfig    `toml:"history"`
+	Context    ContextConfig    `toml:"context"`
+	Redact     RedactConfig     `toml:"redact"`
+	Stdin      StdinConfig      `toml:"stdin"`
+	Validation ValidationConfig `toml:"validation"`
+
+	Profiles map[string]Profile `toml:"profiles"`
+
+	// ProjectFile is the project config file that was applied, if any
+	ProjectFile string `toml:"-"`
+
+	sources map[string]string
+	// baseProvider is the provider before any profile was applied
+	baseProvider string
+}
+
+// Profile is a named set of provider settings, e.g. a personal Gemini key,
+// a company gateway or a local Ollama. Each non-empty field replaces the
+// top-level setting of the same name.
+type Profile struct {
+	Provider      string `toml:"provider"`
+	Endpoint      string `toml:"endpoint"`
+	Model         string `toml:"model"`
+	APIKeyEnv     string `toml:"api_key_env"`
+	APIKeyCommand string `toml:"api_key_command"`
+	Prompt        string `toml:"prompt"`
+}
+
+// ClipboardConfig controls copying generated commands to the clipboard
+type ClipboardConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// TypewriterConfig controls the typewriter output effect
+type TypewriterConfig struct {
+	Enabled bool          `toml:"enabled"`
+	Delay   time.Duration `toml:"delay"`
+}
+
+// HistoryConfig controls the question/command history log
+type HistoryConfig struct {
+	Enabled    bool `toml:"enabled"`
+	MaxEntries int  `toml:"max_entries"`
+}
+
+// StdinConfig controls how input piped to how is sent with the prompt
+type StdinConfig struct {
+	Enabled bool `toml:"enabled"`
+	// MaxBytes caps the input sent; longer input keeps its start and end
+	MaxBytes int `toml:"max_bytes"`
+	// Timeout stops reading a pipe that has been silent this long, so a
+	// pipe nobody closes does not block how forever; 0 waits for the end
+	// of input. Files are always read to the end.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// ValidationConfig controls the syntax and PATH check of generated commands
+type ValidationConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Retry asks the model once for a corrected command when the check
+	// finds problems
+	Retry bool `toml:"retry"`
+}
+
+// RedactConfig adds to the built-in rules that remove secrets from the
+// prompt
+type RedactConfig struct {
+	// Patterns are regular expressions whose matches are redacted
+	Patterns []string `toml:"patterns"`
+}
+
+// ContextConfig controls which system context is sent with the prompt
+type ContextConfig struct {
+	// Disabled lists collectors that must not run
+	Disabled []string `toml:"disabled"`
+	// Enabled lists opt-in collectors that should run
+	Enabled []string `toml:"enabled"`
+	// Timeout bounds each collector; Timeouts overrides it per collector
+	Timeout  time.Duration            `toml:"timeout"`
+	Timeouts map[string]time.Duration `toml:"timeouts"`
+	MaxFiles int                      `toml:"max_files"`
+	// FilesBudget is the approximate number of tokens the file listing
+	// may use
+	FilesBudget int `toml:"files_budget"`
+	// HistoryEntries is how many shell history entries the opt-in
+	// shell_history collector sends
+	HistoryEntries int `toml:"history_entries"`
+	// EnvAllow and EnvDeny add glob patterns to the built-in lists
+	// selecting which environment variable names (never values) are sent
+	EnvAllow []string `toml:"env_allow"`
+	EnvDeny  []string `toml:"env_deny"`
+	// Tools replaces the built-in list of tools to look for on PATH;
+	// ExtraTools adds to it
+	Tools      []string `toml:"tools"`
+	ExtraTools []string `toml:"extra_tools"`
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	return &Config{
+		Provider:    "gemini",
+		Temperature: 1.0,
+		Retries:     3,
+		Timeout:     30 * time.Second,
+		APIKeyStore: StoreKeyring,
+		Clipboard:   ClipboardConfig{Enabled: true},
+		Typewriter:  TypewriterConfig{Delay: 10 * time.Millisecond},
+		History:     HistoryConfig{Enabled: true},
+		Stdin:       StdinConfig{Enabled: true, MaxBytes: 16 * 1024, Timeout: 5 * time.Second},
+		Validation:  ValidationConfig{Enabled: true},
+		Context:     ContextConfig{Timeout: 2 * time.Second, MaxFiles: 40, FilesBudget: 300, HistoryEntries: 10},
+		sources:     map[string]string{},
+	}
+}
+
+// Load builds the effective settings. Precedence, highest first, is:
+// flags > environment > project file > user file > defaults.
+// The returned config is always usable; a non-nil error reports a layer
+// that could not be applied.
+func Load(flags map[string]string) (*Config, error) {
+	cfg := Default()
+	var errs []string
+
+	if err := cfg.loadFile(UserConfigFile(), SourceUser); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		cfg.ProjectFile = FindProjectFile(cwd)
+		if err := cfg.loadFile(cfg.ProjectFile, SourceProject); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	// Profiles sit between the files and the environment. The profile
+	// name itself may still come from a flag or the environment.
+	name := cfg.Profile
+	if value, ok := os.LookupEnv(EnvName("profile")); ok {
+		name = value
+	}
+	if value, ok := flags["profile"]; ok {
+		name = value
+	}
+	if err := cfg.applyProfile(name); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	for _, key := range Keys() {
+		if value, ok := os.LookupEnv(EnvName(key)); ok {
+			if err := cfg.set(key, value, SourceEnv); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", EnvName(key), err))
+			}
+		}
+	}
+
+	for key, value := range flags {
+		if err := cfg.set(key, value, SourceFlag); err != nil {
+			errs = append(errs, fmt.Sprintf("flag %s: %v", key, err))
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return cfg, nil
+}
+
+// ProfileNames returns the names of all configured profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileProvider returns the provider the named profile uses: its own or
+// the top-level one it inherits
+func (c *Config) ProfileProvider(name string) string {
+	if p := c.Profiles[name].Provider; p != "" {
+		return p
+	}
+	if c.baseProvider != "" {
+		return c.baseProvider
+	}
+	return c.Provider
+}
+
+// applyProfile copies the non-empty fields of the named profile onto the
+// top-level settings
+func (c *Config) applyProfile(name string) error {
+	c.baseProvider = c.Provider
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	// A key meant for the top-level provider must not be sent to the
+	// provider or endpoint the profile switches to
+	if profile.Provider != "" && profile.Provider != c.Provider || profile.Endpoint != "" && profile.Endpoint != c.Endpoint {
+		if profile.APIKeyEnv == "" {
+			c.APIKeyEnv = ""
+			c.sources["api_key_env"] = SourceProfile
+		}
+		if profile.APIKeyCommand == "" {
+			c.APIKeyCommand = ""
+			c.sources["api_key_command"] = SourceProfile
+		}
+	}
+
+	v := reflect.ValueOf(profile)
+	for i := 0; i < v.NumField(); i++ {
+		value := v.Field(i).String()
+		if value == "" {
+			continue
+		}
+		if err := c.set(tomlName(v.Type().Field(i)), value, SourceProfile); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// UserConfigFile returns the path of the user config file
+func UserConfigFile() string {
+	return userConfigFile
+}
+
+// EnvName returns the environment variable that overrides a setting
+func EnvName(key string) string {
+	return "HOW_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Validate checks that the settings are within sensible bounds
+func (c *Config) Validate() error {
+	if c.Retries < 1 {
+		return fmt.Errorf("retries must be at least 1")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if c.APIKeyStore != StoreKeyring && c.APIKeyStore != StoreFile {
+		return fmt.Errorf("api_key_store must be %q or %q", StoreKeyring, StoreFile)
+	}
+	if c.History.MaxEntries < 0 {
+		return fmt.Errorf("history.max_entries cannot be negative")
+	}
+	if c.Stdin.MaxBytes < 0 {
+		return fmt.Errorf("stdin.max_bytes cannot be negative")
+	}
+	if c.Stdin.Timeout < 0 {
+		return fmt.Errorf("stdin.timeout must not be negative")
+	}
+	for _, name := range append(append([]string(nil), c.Context.Tools...), c.Context.ExtraTools...) {
+		if strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("context tools must be program names looked up on PATH, not paths: %q", name)
+		}
+	}
+	for _, p := range c.Redact.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("redact.patterns: invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// Get returns the value of a setting formatted as a string
+func (c *Config) Get(key string) (string, error) {
+	v, ok := c.field(key)
+	if !ok {
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	return formatValue(v), nil
+}
+
+// Source reports which layer provided the current value of a setting
+func (c *Config) Source(key string) string {
+	if src, ok := c.sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// Keys returns the dotted names of all settings in sorted order
+func Keys() []string {
+	var keys []string
+	collectKeys(reflect.TypeOf(Config{}), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// SaveSetting writes a single setting to the user config file, keeping
+// the other settings, comments and layout already there
+func SaveSetting(key, value string) error {
+	if errNoHome != nil {
+		return errNoHome
+	}
+
+	// Parse against a scratch config so the stored value is typed and valid
+	scratch := Default()
+	if err := scratch.set(key, value, SourceUser); err != nil {
+		return err
+	}
+	if err := scratch.Validate(); err != nil {
+		return err
+	}
+	v, _ := scratch.field(key)
+
+	raw, err := os.ReadFile(userConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %v", userConfigFile, err)
+	}
+
+	var stored any = v.Interface()
+	if d, ok := stored.(time.Duration); ok {
+		stored = d.String()
+	}
+
+	edited, err := editConfig(raw, key, stored)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", userConfigFile, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(userConfigFile), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(userConfigFile, edited)
+}
+
+// projectDenied lists settings, or whole tables, a project file may not
+// change. A cloned repository must not be able to redirect requests, and
+// with them the API key, to another endpoint, run commands, whether to
+// fetch secrets or as tools probed for their version, or opt the user into
+// sending shell history and command output.
+var projectDenied = []string{
+	"provider",
+	"endpoint",
+	"profile",
+	"profiles",
+	"api_key_env",
+	"api_key_command",
+	"api_key_store",
+	"context.enabled",
+	"context.tools",
+	"context.extra_tools",
+}
+
+// loadFile applies a toml file on top of the current settings
+func (c *Config) loadFile(path, source string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Decode into a separate layer so only the keys present in the file
+	// are copied over
+	var layer Config
+	md, err := toml.DecodeFile(path, &layer)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	var denied []string
+	merged := map[string]bool{}
+	for _, key := range md.Keys() {
+		name := key.String()
+		if source == SourceProject {
+			if rule, ok := deniedBy(name); ok {
+				if !contains(denied, rule) {
+					denied = append(denied, rule)
+				}
+				continue
+			}
+		}
+
+		// Tables like profiles are merged entry by entry. A [profiles.work]
+		// header only lists its own keys, never the profiles table itself,
+		// so look for the map among the key's prefixes.
+		if table, ok := c.mapPrefix(key); ok {
+			if !merged[table] {
+				merged[table] = true
+				dst, _ := c.mapField(table)
+				src, _ := layer.mapField(table)
+				if dst.IsNil() {
+					dst.Set(reflect.MakeMap(dst.Type()))
+				}
+				iter := src.MapRange()
+				for iter.Next() {
+					dst.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			continue
+		}
+
+		dst, ok := c.field(name)
+		if !ok {
+			continue
+		}
+		src, _ := layer.field(name)
+		dst.Set(src)
+		c.sources[name] = source
+	}
+
+	var problems []string
+	if len(denied) > 0 {
+		problems = append(problems, fmt.Sprintf("settings not allowed in project file %s: %s", path, strings.Join(denied, ", ")))
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		var names []string
+		for _, key := range undecoded {
+			names = append(names, key.String())
+		}
+		problems = append(problems, fmt.Sprintf("unknown settings in %s: %s", path, strings.Join(names, ", ")))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// deniedBy returns the entry of projectDenied that keeps a project file
+// from setting key
+func deniedBy(key string) (string, bool) {
+	for _, rule := range projectDenied {
+		if key == rule || strings.HasPrefix(key, rule+".") {
+			return rule, true
+		}
+	}
+	return "", false
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FindProjectFile walks up from dir looking for a project config file and
+// returns its path, or "" if there is none
+func FindProjectFile(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// set parses and stores a setting, recording where it came from
+func (c *Config) set(key, value, source string) error {
+	v, ok := c.field(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if err := parseValue(v, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	c.sources[key] = source
+	return nil
+}
+
+// field resolves a dotted key to the settable leaf field it names
+func (c *Config) field(key string) (reflect.Value, bool) {
+	v, ok := c.lookup(key)
+	if !ok || v.Kind() == reflect.Struct || v.Kind() == reflect.Map {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// mapPrefix returns the map-valued field that key is, or lies within
+func (c *Config) mapPrefix(key toml.Key) (string, bool) {
+	for i := 1; i <= len(key); i++ {
+		name := key[:i].String()
+		if _, ok := c.mapField(name); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// mapField resolves a dotted key to a map-valued field such as profiles
+func (c *Config) mapField(key string) (reflect.Value, bool) {
+	v, ok := c.lookup(key)
+	if !ok || v.Kind() != reflect.Map {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// lookup resolves a dotted key to the struct field it names
+func (c *Config) lookup(key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if tomlName(v.Type().Field(i)) == part {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// collectKeys appends the dotted names of all leaf fields of t
+func collectKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := tomlName(f)
+		if name == "" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Map {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			collectKeys(f.Type, prefix+name+".", keys)
+			continue
+		}
+		*keys = append(*keys, prefix+name)
+	}
+}
+
+// tomlName returns the toml key of an exported field, or "" to skip it
+func tomlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// formatValue renders a setting the same way parseValue accepts it
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Slice:
+		var items []string
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, v.Index(i).String())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// parseBool accepts yes/no and on/off besides what strconv.ParseBool
+// does, as HOW_DEBUG=yes worked before settings were typed
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// parseValue parses s into the setting field v
+func parseValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single collector when no other timeout is set
+const DefaultTimeout = 2 * time.Second
+
+// Fact is a single labelled piece of context rendered into the prompt
+type Fact struct {
+	Label string
+	Value string
+}
+
+// Data is the typed result of a collector. Facts renders it for the prompt.
+type Data interface {
+	Facts() []Fact
+}
+
+// Collector gathers one kind of context
+type Collector struct {
+	// Name identifies the collector in config and output
+	Name string
+	// OptIn collectors only run when listed in Options.Enabled
+	OptIn bool
+	// Timeout overrides DefaultTimeout for this collector
+	Timeout time.Duration
+	// Collect does the work. It should return promptly once ctx is done.
+	Collect func(ctx gocontext.Context, opts Options) (Data, error)
+}
+
+// Section is the outcome of running one collector
+type Section struct {
+	Name     string
+	Data     Data
+	Err      error
+	Duration time.Duration
+}
+
+// SystemContext holds information about the current system environment,
+// one section per collector in registration order
+type SystemContext struct {
+	Sections []Section
+}
+
+// Get returns the data of the named collector, or nil if it did not run or
+// failed
+func (s *SystemContext) Get(name string) Data {
+	for _, section := range s.Sections {
+		if section.Name == name && section.Err == nil {
+			return section.Data
+		}
+	}
+	return nil
+}
+
+// Facts returns the facts of all successful sections in order
+func (s *SystemContext) Facts() []Fact {
+	var facts []Fact
+	for _, section := range s.Sections {
+		if section.Err == nil && section.Data != nil {
+			facts = append(facts, section.Data.Facts()...)
+		}
+	}
+	return facts
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Collector
+)
+
+// Register adds a collector. Registering a name twice replaces the earlier
+// collector in place.
+func Register(c Collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, existing := range registry {
+		if existing.Name == c.Name {
+			registry[i] = c
+			return
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Collectors returns all registered collectors in registration order
+func Collectors() []Collector {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return append([]Collector(nil), registry...)
+}
+
+// enabled reports whether a collector should run under opts
+func (c Collector) enabled(opts Options) bool {
+	for _, name := range opts.Disabled {
+		if name == c.Name {
+			return false
+		}
+	}
+	if !c.OptIn {
+		return true
+	}
+	for _, name := range opts.Enabled {
+		if name == c.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout returns how long a collector may run under opts
+func (c Collector) timeout(opts Options) time.Duration {
+	if d, ok := opts.Timeouts[c.Name]; ok && d > 0 {
+		return d
+	}
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return DefaultTimeout
+}
+
+// Gather runs all enabled collectors in parallel and returns their results
+func Gather(opts Options) *SystemContext {
+	var collectors []Collector
+	for _, c := range Collectors() {
+		if c.enabled(opts) {
+			collectors = append(collectors, c)
+		}
+	}
+
+	sections := make([]Section, len(collectors))
+	var wg sync.WaitGroup
+	for i, c := range collectors {
+		wg.Add(1)
+		go func(i int, c Collector) {
+			defer wg.Done()
+			sections[i] = run(c, opts)
+		}(i, c)
+	}
+	wg.Wait()
+
+	return &SystemContext{Sections: sections}
+}
+
+// run executes a single collector, giving up once its timeout expires
+func run(c Collector, opts Options) Section {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), c.timeout(opts))
+	defer cancel()
+
+	type result struct {
+		data Data
+		err  error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+
+	go func() {
+		defer func() {
+			// A broken collector must not take the whole run down
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("collector panicked: %v", r)}
+			}
+		}()
+		data, err := c.Collect(ctx, opts)
+		done <- result{data, err}
+	}()
+
+	select {
+	case r := <-done:
+		return Section{Name: c.Name, Data: r.data, Err: r.err, Duration: time.Since(start)}
+	case <-ctx.Done():
+		return Section{Name: c.Name, Err: fmt.Errorf("timed out after %s", c.timeout(opts)), Duration: time.Since(start)}
+	}
+}
@@ -1,151 +1,109 @@
 package context
 
 import (
+	gocontext "context"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
-	"strings"
+	"time"
 )
 
-// SystemContext holds information about the current system environment
-type SystemContext struct {
-	OS            string
-	Shell         string
-	CurrentDir    string
-	User          string
-	GitRepo       string
-	Files         string
-	InstalledTools string
+// Options tunes what Gather collects
+type Options struct {
+	// Disabled lists collectors that must not run
+	Disabled []string
+	// Enabled lists opt-in collectors that should run
+	Enabled []string
+	// Timeout bounds each collector; Timeouts overrides it per collector
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// MaxFiles caps the number of file names listed and FilesBudget the
+	// approximate tokens they take
+	MaxFiles    int
+	FilesBudget int
+	// HistoryEntries is how many shell history entries are sent
+	HistoryEntries int
+	// EnvAllow and EnvDeny add to the patterns selecting which
+	// environment variable names are sent
+	EnvAllow []string
+	EnvDeny  []string
+	// Tools replaces the built-in list of tools to look for; ExtraTools
+	// adds to it
+	Tools      []string
+	ExtraTools []string
+	// CacheDir holds results that are slow to compute, such as tool
+	// versions. Caching is off when empty.
+	CacheDir string
 }
 
-// Gather collects system context information
-func Gather() (*SystemContext, error) {
-	ctx := &SystemContext{}
-
-	// Get OS information
-	ctx.OS = fmt.Sprintf("%s %s", runtime.GOOS, getOSVersion())
-
-	// Get shell
-	ctx.Shell = getCurrentTerminal()
-
-	// Get current directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		cwd = "Unknown"
-	}
-	ctx.CurrentDir = cwd
-
-	// Get current user
-	if user := os.Getenv("USER"); user != "" {
-		ctx.User = user
-	} else if user := os.Getenv("USERNAME"); user != "" {
-		ctx.User = user
-	} else {
-		ctx.User = "Unknown"
-	}
-
-	// Check if current directory is a git repository
-	gitDir := filepath.Join(cwd, ".git")
-	if _, err := os.Stat(gitDir); err == nil {
-		ctx.GitRepo = "Yes"
-	} else {
-		ctx.GitRepo = "No"
-	}
-
-	// List files in current directory
-	ctx.Files = listFiles(cwd)
-
-	// Get installed tools
-	ctx.InstalledTools = getInstalledTools()
-
-	return ctx, nil
+// OSInfo describes the operating system
+type OSInfo struct {
+	GOOS    string
+	Version string
+	// Distro is filled from /etc/os-release on Linux
+	Distro *Distro
 }
 
-// getOSVersion returns the OS version/release
-func getOSVersion() string {
-	switch runtime.GOOS {
-	case "linux":
-		// Try to get kernel version
-		out, err := exec.Command("uname", "-r").Output()
-		if err == nil {
-			return strings.TrimSpace(string(out))
-		}
-	case "darwin":
-		// Try to get macOS version
-		out, err := exec.Command("sw_vers", "-productVersion").Output()
-		if err == nil {
-			return strings.TrimSpace(string(out))
-		}
-	case "windows":
-		// Try to get Windows version
-		out, err := exec.Command("ver").Output()
-		if err == nil {
-			return strings.TrimSpace(string(out))
-		}
+func (d OSInfo) Facts() []Fact {
+	desc := fmt.Sprintf("%s %s", d.GOOS, d.Version)
+	if d.Distro != nil {
+		desc += " (" + d.Distro.String() + ")"
 	}
-	return "Unknown"
+	return []Fact{{"OS", desc}}
 }
 
-// getCurrentTerminal returns the name of the current terminal/shell
-func getCurrentTerminal() string {
-	// Check common shell environment variables
-	if shell := os.Getenv("SHELL"); shell != "" {
-		return filepath.Base(shell)
-	}
+// DirInfo holds the current working directory
+type DirInfo struct {
+	Path string
+}
 
-	// Try to get parent process name (similar to Python psutil approach)
-	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
-		ppid := os.Getppid()
-		// Try to read /proc/<ppid>/comm (Linux)
-		if runtime.GOOS == "linux" {
-			commPath := fmt.Sprintf("/proc/%d/comm", ppid)
-			data, err := os.ReadFile(commPath)
-			if err == nil {
-				return strings.TrimSpace(string(data))
-			}
-		}
-		// Try using ps command
-		out, err := exec.Command("ps", "-p", fmt.Sprintf("%d", ppid), "-o", "comm=").Output()
-		if err == nil {
-			return strings.TrimSpace(string(out))
-		}
-	}
+func (d DirInfo) Facts() []Fact {
+	return []Fact{{"CWD", d.Path}}
+}
 
-	return "Unknown"
+// UserInfo holds the current user name
+type UserInfo struct {
+	Name string
 }
 
-// listFiles returns a comma-separated list of files in the directory
-func listFiles(dir string) string {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return "Error listing files"
-	}
+func (d UserInfo) Facts() []Fact {
+	return []Fact{{"User", d.Name}}
+}
 
-	var files []string
-	maxFiles := 20
-	for i, entry := range entries {
-		if i >= maxFiles {
-			files = append(files, "...")
-			break
+func init() {
+	Register(Collector{Name: "os", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		info := OSInfo{GOOS: sys.GOOS(), Version: getOSVersion(ctx)}
+		if info.GOOS == "linux" {
+			info.Distro = readDistro()
 		}
-		files = append(files, entry.Name())
-	}
+		return info, nil
+	}})
 
-	return strings.Join(files, ", ")
-}
+	Register(Collector{Name: "shell", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return collectShell(ctx), nil
+	}})
 
-// getInstalledTools checks for commonly installed development tools
-func getInstalledTools() string {
-	tools := []string{"git", "npm", "node", "python", "docker", "pip", "go", "rustc", "cargo", "java", "mvn", "gradle"}
-	var installed []string
+	Register(Collector{Name: "cwd", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		cwd, err := sys.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return DirInfo{Path: cwd}, nil
+	}})
 
-	for _, tool := range tools {
-		if _, err := exec.LookPath(tool); err == nil {
-			installed = append(installed, tool)
+	Register(Collector{Name: "user", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		if user := sys.Getenv("USER"); user != "" {
+			return UserInfo{Name: user}, nil
 		}
-	}
+		if user := sys.Getenv("USERNAME"); user != "" {
+			return UserInfo{Name: user}, nil
+		}
+		return UserInfo{Name: "Unknown"}, nil
+	}})
 
-	return strings.Join(installed, ", ")
+	Register(Collector{Name: "files", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		cwd, err := sys.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return listFiles(ctx, cwd, opts.MaxFiles, opts.FilesBudget)
+	}})
 }
@@ -0,0 +1,225 @@
+package context
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// gitRepo adds the output of the git commands the collectors run in a
+// clean repository at root on branch main
+func gitRepo(s *fakeSystem, root string) {
+	git := "git --no-optional-locks "
+	s.path["git"] = "/usr/bin/git"
+	s.commands[git+"rev-parse --show-toplevel --absolute-git-dir"] = root + "\n" + root + "/.git"
+	s.commands[git+"status --porcelain=v2 --branch"] = strings.Join([]string{
+		"# branch.oid 4f2a9c1d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a39",
+		"# branch.head main",
+		"# branch.upstream origin/main",
+		"# branch.ab +1 -0",
+		"1 .M N... 100644 100644 100644 3b18e5 3b18e5 main.go",
+		"? notes.txt",
+	}, "\n")
+	s.commands[git+"remote -v"] = "origin\tgit@github.com:ada/how.git (fetch)\norigin\tgit@github.com:ada/how.git (push)"
+	s.commands[git+"log -n "+strconv.Itoa(recentCommits)+" --format=%h %s"] = strings.Join([]string{
+		"4f2a9c1 Add --run",
+		"9e8d7c6 Detect WSL",
+		"7d6e5f4 List files by relevance",
+		"3c2b1a0 Read config from XDG paths",
+		"1a2b3c4 Initial commit",
+	}, "\n")
+	s.commands[git+"ls-files --cached --others --exclude-standard"] = "go.mod\nmain.go\nREADME.md\nnotes.txt"
+	s.commands[git+"check-ignore --stdin"] = ""
+	s.exits[git+"check-ignore --stdin"] = 1
+	s.commands["git --version"] = "git version 2.43.0"
+	s.files[root+"/.git/HEAD"] = "ref: refs/heads/main\n"
+}
+
+// project adds a small Go module at dir
+func project(s *fakeSystem, dir string) {
+	s.files[dir+"/go.mod"] = "module github.com/ada/how\n\ngo 1.24\n"
+	s.files[dir+"/main.go"] = "package main\n"
+	s.files[dir+"/README.md"] = "# how\n"
+	s.files[dir+"/notes.txt"] = "todo\n"
+}
+
+func newFakeSystem(goos string) *fakeSystem {
+	return &fakeSystem{
+		goos:     goos,
+		env:      map[string]string{},
+		files:    map[string]string{},
+		links:    map[string]string{},
+		path:     map[string]string{},
+		commands: map[string]string{},
+		exits:    map[string]int{},
+	}
+}
+
+func ubuntuBashGit() *fakeSystem {
+	s := newFakeSystem("linux")
+	s.cwd, s.home, s.ppid = "/home/ada/src/how", "/home/ada", 4100
+	s.env = map[string]string{
+		"USER": "ada", "HOME": "/home/ada", "SHELL": "/bin/bash",
+		"PATH": "/usr/local/bin:/usr/bin:/bin", "GOPATH": "/home/ada/go", "EDITOR": "vim",
+	}
+	s.files["/etc/os-release"] = "NAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nID=ubuntu\nID_LIKE=debian\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\n"
+	s.files["/proc/4100/stat"] = "4100 (bash) S 4000 4100 4100 34816 0"
+	s.links["/proc/4100/exe"] = "/usr/bin/bash"
+	s.files["/proc/1/comm"] = "systemd\n"
+	s.files["/run/systemd/system/.keep"] = ""
+	s.files["/sys/class/dmi/id/sys_vendor"] = "Dell Inc.\n"
+	s.commands["uname -r"] = "6.8.0-45-generic"
+	s.commands["/usr/bin/bash --version"] = "GNU bash, version 5.2.21(1)-release (x86_64-pc-linux-gnu)"
+	s.path["apt"] = "/usr/bin/apt"
+	s.path["snap"] = "/usr/bin/snap"
+	s.path["go"] = "/usr/local/go/bin/go"
+	s.commands["go version"] = "go version go1.24.2 linux/amd64"
+	s.path["python3"] = "/usr/bin/python3"
+	s.commands["python3 --version"] = "Python 3.12.3"
+	gitRepo(s, s.cwd)
+	project(s, s.cwd)
+	return s
+}
+
+func alpineContainer() *fakeSystem {
+	s := newFakeSystem("linux")
+	s.cwd, s.home, s.ppid = "/app", "/root", 7
+	s.env = map[string]string{"HOME": "/root", "PATH": "/usr/local/bin:/usr/bin:/bin", "NODE_ENV": "production"}
+	s.files["/etc/os-release"] = "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.20.3\nPRETTY_NAME=\"Alpine Linux v3.20\"\n"
+	s.files["/.dockerenv"] = ""
+	s.files["/proc/7/stat"] = "7 (sh) S 1 7 7 34816 0"
+	s.files["/proc/7/comm"] = "sh\n"
+	s.links["/proc/7/exe"] = "/bin/busybox"
+	s.files["/proc/1/comm"] = "node\n"
+	s.commands["uname -r"] = "6.6.32-linuxkit"
+	s.path["apk"] = "/sbin/apk"
+	s.path["node"] = "/usr/local/bin/node"
+	s.commands["node --version"] = "v20.17.0"
+	s.files["/app/package.json"] = "{\"name\": \"api\", \"scripts\": {\"start\": \"node server.js\"}}\n"
+	s.files["/app/server.js"] = "require('http')\n"
+	// No git binary, but the repository was copied in
+	s.files["/app/.git/HEAD"] = "ref: refs/heads/main\n"
+	return s
+}
+
+func macOSZsh() *fakeSystem {
+	s := newFakeSystem("darwin")
+	s.cwd, s.home, s.ppid = "/Users/ada/src/how", "/Users/ada", 812
+	s.env = map[string]string{
+		"USER": "ada", "HOME": "/Users/ada", "SHELL": "/bin/zsh",
+		"PATH": "/opt/homebrew/bin:/usr/bin:/bin", "HOMEBREW_PREFIX": "/opt/homebrew",
+		"AWS_PROFILE": "dev", "SSH_CONNECTION": "10.0.0.2 50022 10.0.0.5 22",
+	}
+	s.commands["sw_vers"] = "ProductName:\t\tmacOS\nProductVersion:\t\t14.4.1\nBuildVersion:\t\t23E224"
+	s.commands["ps -o ppid=,comm= -p 812"] = "  805 -zsh"
+	s.path["zsh"] = "/bin/zsh"
+	s.commands["/bin/zsh --version"] = "zsh 5.9 (x86_64-apple-darwin23.0)"
+	s.path["brew"] = "/opt/homebrew/bin/brew"
+	s.files["/Users/ada/.aws/config"] = "[profile dev]\nregion = eu-west-1\n"
+	gitRepo(s, s.cwd)
+	s.commands["git --version"] = "git version 2.39.3 (Apple Git-146)"
+	project(s, s.cwd)
+	return s
+}
+
+func wslUbuntu() *fakeSystem {
+	s := ubuntuBashGit()
+	s.cwd = "/mnt/c/Users/ada/project"
+	s.env["WSL_DISTRO_NAME"] = "Ubuntu"
+	s.env["WSLENV"] = "WT_SESSION"
+	s.files["/proc/version"] = "Linux version 5.15.153.1-microsoft-standard-WSL2 (root@941d701f84f1)"
+	s.commands["uname -r"] = "5.15.153.1-microsoft-standard-WSL2"
+	delete(s.files, "/run/systemd/system/.keep")
+	s.files["/proc/1/comm"] = "init\n"
+	delete(s.files, "/sys/class/dmi/id/sys_vendor")
+	s.files["/mnt/c/Users/ada/project/Makefile"] = "all:\n"
+	// Not a repository
+	delete(s.commands, "git --no-optional-locks rev-parse --show-toplevel --absolute-git-dir")
+	s.exits["git --no-optional-locks rev-parse --show-toplevel --absolute-git-dir"] = 128
+	delete(s.commands, "git --no-optional-locks ls-files --cached --others --exclude-standard")
+	s.exits["git --no-optional-locks ls-files --cached --others --exclude-standard"] = 128
+	s.exits["git --no-optional-locks check-ignore --stdin"] = 128
+	return s
+}
+
+// ubuntuSubdir runs from a package directory below the repository root,
+// where there is no .git of its own
+func ubuntuSubdir() *fakeSystem {
+	s := ubuntuBashGit()
+	s.cwd = "/home/ada/src/how/cmd/how"
+	s.files[s.cwd+"/main.go"] = "package main\n"
+	s.files[s.cwd+"/stdin.go"] = "package main\n"
+	s.commands["git --no-optional-locks ls-files --cached --others --exclude-standard"] = "main.go\nstdin.go"
+	return s
+}
+
+// worktreeNoGit runs from a subdirectory of a linked worktree, where .git
+// is a file pointing at the main repository, on a machine without git
+func worktreeNoGit() *fakeSystem {
+	s := alpineContainer()
+	delete(s.files, "/app/.git/HEAD")
+	s.cwd = "/src/how-feature/internal"
+	s.files["/src/how-feature/.git"] = "gitdir: /src/how/.git/worktrees/how-feature\n"
+	s.files["/src/how-feature/go.mod"] = "module github.com/ada/how\n\ngo 1.24\n"
+	s.files["/src/how-feature/internal/doc.go"] = "package internal\n"
+	return s
+}
+
+// TestGatherGolden runs every default collector against simulated
+// machines and compares what they report with testdata/<name>.golden.
+// Run with -update after an intended change.
+func TestGatherGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		sys  *fakeSystem
+	}{
+		{"ubuntu-bash-git", ubuntuBashGit()},
+		{"alpine-container", alpineContainer()},
+		{"macos-zsh", macOSZsh()},
+		{"wsl", wslUbuntu()},
+		{"ubuntu-subdir", ubuntuSubdir()},
+		{"worktree-no-git", worktreeNoGit()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSystem(t, tt.sys)
+			got := render(Gather(Options{MaxFiles: 40, FilesBudget: 300}))
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("context differs from %s:\n--- got\n%s\n--- want\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+// render prints each section's facts, or its error, under its name
+func render(ctx *SystemContext) string {
+	var b strings.Builder
+	for _, section := range ctx.Sections {
+		fmt.Fprintf(&b, "[%s]\n", section.Name)
+		if section.Err != nil {
+			fmt.Fprintf(&b, "error: %v\n", section.Err)
+			continue
+		}
+		for _, fact := range section.Data.Facts() {
+			fmt.Fprintf(&b, "%s: %s\n", fact.Label, fact.Value)
+		}
+	}
+	return b.String()
+}
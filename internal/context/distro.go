@@ -0,0 +1,110 @@
+package context
+
+import (
+	"bufio"
+	gocontext "context"
+	"strconv"
+	"strings"
+)
+
+// Distro identifies a Linux distribution as described by os-release(5)
+type Distro struct {
+	ID         string
+	IDLike     []string
+	Name       string
+	VersionID  string
+	PrettyName string
+}
+
+// String renders the distribution as e.g. "Ubuntu 24.04 LTS, like debian"
+func (d Distro) String() string {
+	name := d.PrettyName
+	if name == "" {
+		name = strings.TrimSpace(d.Name + " " + d.VersionID)
+	}
+	if name == "" {
+		name = d.ID
+	}
+	if len(d.IDLike) > 0 {
+		name += ", like " + strings.Join(d.IDLike, " ")
+	}
+	return name
+}
+
+// osReleasePaths are checked in order, as specified by os-release(5)
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// readDistro parses the first os-release file found, or returns nil
+func readDistro() *Distro {
+	for _, path := range osReleasePaths {
+		if data, err := sys.ReadFile(path); err == nil {
+			return parseOSRelease(string(data))
+		}
+	}
+	return nil
+}
+
+// parseOSRelease parses the KEY=value lines of an os-release file
+func parseOSRelease(data string) *Distro {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+
+	d := &Distro{
+		ID:         values["ID"],
+		Name:       values["NAME"],
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}
+	if like := values["ID_LIKE"]; like != "" {
+		d.IDLike = strings.Fields(like)
+	}
+	return d
+}
+
+// PackageManagersInfo lists the system package managers found on PATH
+type PackageManagersInfo struct {
+	Available []string
+}
+
+func (d PackageManagersInfo) Facts() []Fact {
+	if len(d.Available) == 0 {
+		return []Fact{{"Package Managers", "none found"}}
+	}
+	return []Fact{{"Package Managers", strings.Join(d.Available, ", ")}}
+}
+
+// packageManagers are probed in this order; the distribution's native
+// manager comes first on a typical system
+var packageManagers = []string{
+	"apt", "dnf", "yum", "pacman", "apk", "zypper", "emerge", "xbps-install",
+	"nix", "brew", "port", "snap", "flatpak",
+	"winget", "choco", "scoop",
+}
+
+func init() {
+	Register(Collector{Name: "packages", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		var info PackageManagersInfo
+		for _, pm := range packageManagers {
+			if _, err := sys.LookPath(pm); err == nil {
+				info.Available = append(info.Available, pm)
+			}
+		}
+		return info, nil
+	}})
+}
@@ -0,0 +1,94 @@
+package context
+
+import (
+	gocontext "context"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxEnvNames caps the environment variable names sent
+const maxEnvNames = 60
+
+// DefaultEnvAllow are patterns of environment variable names worth
+// mentioning; config patterns add to them
+var DefaultEnvAllow = []string{
+	"*_URL", "*_URI", "*_DSN", "*_HOST", "*_PORT", "*_HOME", "*_ROOT",
+	"*_DIR", "*PATH", "*_ENV", "*_PROFILE", "*_REGION", "*_PROJECT",
+	"*_NAMESPACE", "*_TOKEN", "*_KEY", "*_SECRET", "*_PASSWORD",
+	"KUBECONFIG", "VIRTUAL_ENV", "CONDA_*", "DOCKER_*", "AWS_*", "GOOGLE_*",
+	"CLOUDSDK_*", "AZURE_*", "TF_*", "ANSIBLE_*", "GO*", "CARGO_*", "RUST*",
+	"NODE_*", "NPM_*", "PYTHON*", "PIP_*", "JAVA_*", "MAVEN_*", "GRADLE_*",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "EDITOR", "VISUAL", "PAGER",
+	"CI", "GITHUB_*", "GITLAB_*",
+}
+
+// DefaultEnvDeny are patterns excluded even when allowed, because they are
+// always set or only concern the terminal; config patterns add to them
+var DefaultEnvDeny = []string{
+	"PATH", "XDG_*", "HOW_*", "SSH_*", "TERM*", "LS_COLORS", "PS1", "PS2",
+	"DBUS_*", "WSL*", "*_PID", "MANPATH", "INFOPATH", "__*",
+}
+
+// EnvInfo lists the names, never the values, of relevant environment
+// variables that are set
+type EnvInfo struct {
+	Names     []string
+	Truncated bool
+}
+
+func (d EnvInfo) Facts() []Fact {
+	if len(d.Names) == 0 {
+		return nil
+	}
+	names := strings.Join(d.Names, ", ")
+	if d.Truncated {
+		names += ", ..."
+	}
+	return []Fact{{"Environment Variables Set (names only)", names}}
+}
+
+func init() {
+	Register(Collector{Name: "env", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		allow := append(append([]string(nil), DefaultEnvAllow...), opts.EnvAllow...)
+		deny := append(append([]string(nil), DefaultEnvDeny...), opts.EnvDeny...)
+		return envNames(sys.Environ(), allow, deny), nil
+	}})
+}
+
+// envNames picks the names in environ matching an allow pattern and no
+// deny pattern. Matching ignores case, as Windows does.
+func envNames(environ, allow, deny []string) EnvInfo {
+	var info EnvInfo
+	seen := map[string]bool{}
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		// Windows keeps per-drive directories in names like "=C:"
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !matchesEnv(allow, name) || matchesEnv(deny, name) {
+			continue
+		}
+		info.Names = append(info.Names, name)
+	}
+
+	sort.Strings(info.Names)
+	if len(info.Names) > maxEnvNames {
+		info.Names = info.Names[:maxEnvNames]
+		info.Truncated = true
+	}
+	return info
+}
+
+// matchesEnv reports whether name matches one of the glob patterns
+func matchesEnv(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,470 @@
+package context
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxFiles caps the number of entries listed
+	DefaultMaxFiles = 40
+	// DefaultFilesBudget is the approximate number of tokens the file
+	// listing may use
+	DefaultFilesBudget = 300
+	// maxChildren caps the entries listed inside each directory
+	maxChildren = 8
+	// largeFile is the size from which a file's size is shown
+	largeFile = 1 << 20
+	// maxStatFiles bounds the files counted for extension stats
+	maxStatFiles = 20000
+	// statsTimeout bounds the extension stats, which list the whole
+	// subtree, so that a large tree can't cost the listing itself
+	statsTimeout = 500 * time.Millisecond
+	// topExtensions is how many extensions the stats report
+	topExtensions = 6
+)
+
+// importantFiles are listed first: they tell the most about a directory
+var importantFiles = map[string]bool{
+	"README.md": true, "README": true, "Makefile": true, "justfile": true,
+	"Taskfile.yml": true, "Dockerfile": true, "docker-compose.yml": true,
+	"compose.yaml": true, "go.mod": true, "package.json": true,
+	"Cargo.toml": true, "pyproject.toml": true, "requirements.txt": true,
+	"setup.py": true, "pom.xml": true, "build.gradle": true,
+	"build.gradle.kts": true, "Gemfile": true, "composer.json": true,
+	"CMakeLists.txt": true, "flake.nix": true, ".how.toml": true,
+}
+
+// visibleDotfiles are hidden files worth listing
+var visibleDotfiles = map[string]bool{
+	".github": true, ".gitlab-ci.yml": true, ".gitignore": true,
+	".dockerignore": true, ".env": true, ".env.example": true,
+	".envrc": true, ".devcontainer": true, ".how.toml": true,
+	".tool-versions": true, ".nvmrc": true, ".python-version": true,
+	".pre-commit-config.yaml": true, ".editorconfig": true,
+}
+
+// noiseDirs are listed last and never descended into
+var noiseDirs = map[string]bool{
+	"node_modules": true, "bower_components": true, "__pycache__": true,
+	".venv": true, "venv": true, ".tox": true, ".mypy_cache": true,
+	".pytest_cache": true, ".next": true, ".nuxt": true, ".terraform": true,
+	".gradle": true,
+}
+
+// FileEntry is a file or directory in the listing
+type FileEntry struct {
+	Name string
+	Dir  bool
+	Link bool
+	Exec bool
+	// Size is only set for files of at least 1 MiB
+	Size     int64
+	Children []FileEntry
+	// More counts children left out
+	More int
+
+	rank    int
+	modTime int64
+}
+
+func (e FileEntry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Name)
+	switch {
+	case e.Link:
+		b.WriteString("@")
+	case e.Dir:
+		b.WriteString("/")
+	case e.Exec:
+		b.WriteString("*")
+	}
+	if e.Size > 0 {
+		fmt.Fprintf(&b, " (%s)", humanSize(e.Size))
+	}
+	if len(e.Children) > 0 {
+		var children []string
+		for _, c := range e.Children {
+			children = append(children, c.String())
+		}
+		if e.More > 0 {
+			children = append(children, fmt.Sprintf("+%d more", e.More))
+		}
+		b.WriteString("{" + strings.Join(children, ", ") + "}")
+	}
+	return b.String()
+}
+
+// ExtCount is the number of files with an extension
+type ExtCount struct {
+	Ext   string
+	Count int
+}
+
+// FilesInfo is a ranked, shallow listing of the working directory
+type FilesInfo struct {
+	Entries []FileEntry
+	// Omitted counts top-level entries left out by the limits
+	Omitted int
+	// Hidden and Ignored count entries skipped as dotfiles or by
+	// .gitignore
+	Hidden     int
+	Ignored    int
+	Extensions []ExtCount
+}
+
+func (d FilesInfo) Facts() []Fact {
+	var names []string
+	for _, e := range d.Entries {
+		names = append(names, e.String())
+	}
+	if d.Omitted > 0 {
+		names = append(names, fmt.Sprintf("+%d more", d.Omitted))
+	}
+	files := strings.Join(names, ", ")
+	if files == "" {
+		files = "(empty)"
+	}
+	if d.Hidden+d.Ignored > 0 {
+		files += fmt.Sprintf(" (not shown: %d hidden, %d ignored)", d.Hidden, d.Ignored)
+	}
+	facts := []Fact{{"Files", files}}
+
+	if len(d.Extensions) > 0 {
+		var exts []string
+		for _, e := range d.Extensions {
+			exts = append(exts, fmt.Sprintf("%s %d", e.Ext, e.Count))
+		}
+		facts = append(facts, Fact{"File Types", strings.Join(exts, ", ")})
+	}
+	return facts
+}
+
+// Ranks, lowest first
+const (
+	rankImportant = iota
+	rankDir
+	rankFile
+	rankNoise
+)
+
+// listFiles lists dir and the first level of its subdirectories, most
+// relevant entries first, within maxFiles entries and about budget tokens
+func listFiles(ctx gocontext.Context, dir string, maxFiles, budget int) (FilesInfo, error) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	if budget <= 0 {
+		budget = DefaultFilesBudget
+	}
+
+	var info FilesInfo
+	top, err := readEntries(dir, &info)
+	if err != nil {
+		return FilesInfo{}, fmt.Errorf("error listing files: %v", err)
+	}
+
+	// Ask git which entries are ignored, one level down included
+	var candidates []string
+	children := map[string][]FileEntry{}
+	for _, e := range top {
+		candidates = append(candidates, e.Name)
+		if e.Dir && !e.Link && e.rank != rankNoise {
+			children[e.Name], _ = readEntries(filepath.Join(dir, e.Name), nil)
+			for _, c := range children[e.Name] {
+				candidates = append(candidates, e.Name+"/"+c.Name)
+			}
+		}
+	}
+	ignored := ignoredPaths(ctx, dir, candidates)
+
+	count := 0
+	for _, e := range top {
+		if ignored[e.Name] {
+			info.Ignored++
+			continue
+		}
+		cost := tokens(e.String())
+		if count >= maxFiles || cost > budget {
+			info.Omitted++
+			continue
+		}
+		budget -= cost
+		count++
+		info.Entries = append(info.Entries, e)
+	}
+
+	// Spend what is left on the contents of the listed directories
+	for i := range info.Entries {
+		e := &info.Entries[i]
+		for _, c := range children[e.Name] {
+			if ignored[e.Name+"/"+c.Name] {
+				continue
+			}
+			cost := tokens(c.String()) + 1
+			if len(e.Children) >= maxChildren || count >= maxFiles || cost > budget {
+				e.More++
+				continue
+			}
+			budget -= cost
+			count++
+			e.Children = append(e.Children, c)
+		}
+	}
+
+	info.Extensions = extensionStats(ctx, dir)
+	return info, nil
+}
+
+// readEntries returns the visible entries of dir, ranked. Hidden entries
+// are counted in info when it is not nil.
+func readEntries(dir string, info *FilesInfo) ([]FileEntry, error) {
+	dirEntries, err := sys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FileEntry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if strings.HasPrefix(name, ".") && !visibleDotfiles[name] && !noiseDirs[name] {
+			if info != nil {
+				info.Hidden++
+			}
+			continue
+		}
+
+		e := FileEntry{Name: name, Dir: de.IsDir(), Link: de.Type()&os.ModeSymlink != 0}
+		if fi, err := de.Info(); err == nil {
+			e.modTime = fi.ModTime().Unix()
+			if fi.Mode().IsRegular() {
+				e.Exec = fi.Mode()&0111 != 0
+				if fi.Size() >= largeFile {
+					e.Size = fi.Size()
+				}
+			}
+		}
+
+		switch {
+		case importantFiles[name] || strings.HasPrefix(name, "README"):
+			e.rank = rankImportant
+		case noiseDirs[name]:
+			e.rank = rankNoise
+		case e.Dir:
+			e.rank = rankDir
+		default:
+			e.rank = rankFile
+		}
+		entries = append(entries, e)
+	}
+
+	// Within a rank, recently modified entries first
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].rank != entries[j].rank {
+			return entries[i].rank < entries[j].rank
+		}
+		if entries[i].modTime != entries[j].modTime {
+			return entries[i].modTime > entries[j].modTime
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// ignoredPaths returns which of paths (relative to dir, slash-separated)
+// are ignored, asking git so nested and global ignore files count, and
+// falling back to dir's own .gitignore
+func ignoredPaths(ctx gocontext.Context, dir string, paths []string) map[string]bool {
+	ignored := map[string]bool{}
+	if len(paths) == 0 {
+		return ignored
+	}
+
+	out, err := sys.Run(ctx, command{
+		Name:  "git",
+		Args:  []string{"--no-optional-locks", "check-ignore", "--stdin"},
+		Dir:   dir,
+		Stdin: strings.Join(paths, "\n") + "\n",
+	})
+	// Exit status 1 means nothing is ignored; anything else means git is
+	// missing or dir is not in a repository
+	if code := exitCode(err); code == 0 || code == 1 {
+		for _, line := range strings.Split(out, "\n") {
+			ignored[strings.TrimSuffix(line, "/")] = true
+		}
+		return ignored
+	}
+
+	patterns := readGitignore(filepath.Join(dir, ".gitignore"))
+	for _, p := range paths {
+		isDir := false
+		if fi, err := sys.Stat(filepath.Join(dir, p)); err == nil {
+			isDir = fi.IsDir()
+		}
+		if matchGitignore(patterns, p, isDir) {
+			ignored[p] = true
+		}
+	}
+	return ignored
+}
+
+// gitignorePattern is a simplified .gitignore rule
+type gitignorePattern struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	// anchored patterns match from the top rather than any path component
+	anchored bool
+}
+
+// readGitignore parses a .gitignore file, ignoring a missing one
+func readGitignore(file string) []gitignorePattern {
+	f, err := sys.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []gitignorePattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p gitignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		p.pattern = line
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// matchGitignore reports whether rel (slash-separated) is ignored; a
+// later matching rule overrides an earlier one, as in git
+func matchGitignore(patterns []gitignorePattern, rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		var matched bool
+		if p.anchored {
+			matched, _ = path.Match(p.pattern, rel)
+		} else {
+			matched, _ = path.Match(p.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// extensionStats counts files by extension below dir, using the files git
+// knows about when dir is in a repository and a shallow walk otherwise. It
+// returns nothing if that takes longer than statsTimeout.
+func extensionStats(ctx gocontext.Context, dir string) []ExtCount {
+	ctx, cancel := gocontext.WithTimeout(ctx, statsTimeout)
+	defer cancel()
+
+	counts := map[string]int{}
+	add := func(name string) {
+		if ext := strings.ToLower(filepath.Ext(name)); ext != "" && ext != name {
+			counts[ext]++
+		}
+	}
+
+	git := command{Name: "git", Args: []string{"--no-optional-locks", "ls-files", "--cached", "--others", "--exclude-standard"}, Dir: dir}
+	if out, err := sys.Run(ctx, git); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(out))
+		for n := 0; scanner.Scan() && n < maxStatFiles; n++ {
+			add(path.Base(scanner.Text()))
+		}
+	} else if ctx.Err() != nil {
+		return nil
+	} else {
+		patterns := readGitignore(filepath.Join(dir, ".gitignore"))
+		n := 0
+		var walk func(rel string)
+		walk = func(rel string) {
+			entries, err := sys.ReadDir(filepath.Join(dir, filepath.FromSlash(rel)))
+			if err != nil {
+				return
+			}
+			for _, d := range entries {
+				if ctx.Err() != nil || n >= maxStatFiles {
+					return
+				}
+				p := path.Join(rel, d.Name())
+				if d.IsDir() {
+					if !noiseDirs[d.Name()] && !strings.HasPrefix(d.Name(), ".") && strings.Count(p, "/") < 2 && !matchGitignore(patterns, p, true) {
+						walk(p)
+					}
+					continue
+				}
+				if matchGitignore(patterns, p, false) {
+					continue
+				}
+				n++
+				add(d.Name())
+			}
+		}
+		walk("")
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	var stats []ExtCount
+	for ext, count := range counts {
+		stats = append(stats, ExtCount{ext, count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Ext < stats[j].Ext
+	})
+	if len(stats) > topExtensions {
+		stats = stats[:topExtensions]
+	}
+	return stats
+}
+
+// tokens roughly estimates the tokens s takes in the prompt
+func tokens(s string) int {
+	return (len(s)+3)/4 + 1
+}
+
+// humanSize formats a byte count, e.g. "12 MB"
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.0f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
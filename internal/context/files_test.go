@@ -0,0 +1,32 @@
+package context
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+)
+
+// TestListFilesSlowStats checks that a git ls-files that outlasts the
+// stats deadline still leaves the top-level listing
+func TestListFilesSlowStats(t *testing.T) {
+	s := ubuntuBashGit()
+	s.hangs = map[string]bool{"git --no-optional-locks ls-files --cached --others --exclude-standard": true}
+	useSystem(t, s)
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), DefaultTimeout)
+	defer cancel()
+	start := time.Now()
+	info, err := listFiles(ctx, s.cwd, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultTimeout {
+		t.Errorf("listing took %s, longer than the collector timeout", elapsed)
+	}
+	if len(info.Entries) != 4 {
+		t.Errorf("listed %v, want the 4 top-level files", info.Entries)
+	}
+	if info.Extensions != nil {
+		t.Errorf("got extension stats %v after the stats timed out", info.Extensions)
+	}
+}
@@ -0,0 +1,264 @@
+package context
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// recentCommits is how many commit subjects are sent with the context
+const recentCommits = 5
+
+// GitInfo describes the git repository around the working directory
+type GitInfo struct {
+	IsRepo bool
+	Root   string
+
+	// Branch is empty when HEAD is detached, in which case Head holds the
+	// abbreviated commit
+	Branch   string
+	Head     string
+	Upstream string
+	Ahead    int
+	Behind   int
+
+	// HasStatus is false when git could not run, leaving the counts unknown
+	HasStatus  bool
+	Staged     int
+	Unstaged   int
+	Untracked  int
+	Conflicted int
+
+	// State is an operation in progress: rebase, merge, cherry-pick,
+	// revert or bisect
+	State string
+
+	Remotes []GitRemote
+	Commits []string
+}
+
+// GitRemote is a configured remote with credentials stripped from its URL
+type GitRemote struct {
+	Name string
+	URL  string
+}
+
+func (d GitInfo) Facts() []Fact {
+	if !d.IsRepo {
+		return []Fact{{"Git Repo", "No"}}
+	}
+
+	facts := []Fact{{"Git Repo", fmt.Sprintf("Yes (root: %s)", d.Root)}}
+
+	if d.Branch != "" || d.Head != "" {
+		branch := d.Branch
+		if branch == "" {
+			branch = "detached HEAD at " + d.Head
+		}
+		if d.Upstream != "" {
+			branch += fmt.Sprintf(" (tracking %s, ahead %d, behind %d)", d.Upstream, d.Ahead, d.Behind)
+		} else if d.Branch != "" {
+			branch += " (no upstream)"
+		}
+		facts = append(facts, Fact{"Git Branch", branch})
+	}
+
+	if d.HasStatus {
+		status := "clean"
+		if d.Staged+d.Unstaged+d.Untracked+d.Conflicted > 0 {
+			status = fmt.Sprintf("%d staged, %d modified, %d untracked, %d conflicted", d.Staged, d.Unstaged, d.Untracked, d.Conflicted)
+		}
+		facts = append(facts, Fact{"Git Status", status})
+	}
+
+	if d.State != "" {
+		facts = append(facts, Fact{"Git In Progress", d.State})
+	}
+
+	if len(d.Remotes) > 0 {
+		var remotes []string
+		for _, r := range d.Remotes {
+			remotes = append(remotes, fmt.Sprintf("%s (%s)", r.Name, r.URL))
+		}
+		facts = append(facts, Fact{"Git Remotes", strings.Join(remotes, ", ")})
+	}
+
+	if len(d.Commits) > 0 {
+		facts = append(facts, Fact{"Recent Commits", strings.Join(d.Commits, "; ")})
+	}
+
+	return facts
+}
+
+func init() {
+	Register(Collector{Name: "git", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		cwd, err := sys.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return collectGit(ctx, cwd), nil
+	}})
+}
+
+// collectGit describes the repository containing dir. Without a git binary
+// it can only tell whether dir is inside a repository.
+func collectGit(ctx gocontext.Context, dir string) GitInfo {
+	git := func(args ...string) (string, error) {
+		return sys.Run(ctx, command{Name: "git", Args: append([]string{"--no-optional-locks"}, args...), Dir: dir})
+	}
+
+	out, err := git("rev-parse", "--show-toplevel", "--absolute-git-dir")
+	if err != nil {
+		// Either not a repository or no usable git; find the root by hand
+		if root := findGitRoot(dir); root != "" {
+			return GitInfo{IsRepo: true, Root: root}
+		}
+		return GitInfo{}
+	}
+
+	info := GitInfo{IsRepo: true}
+	lines := strings.Split(out, "\n")
+	gitDir := ""
+	if len(lines) == 2 {
+		info.Root = lines[0]
+		gitDir = lines[1]
+	} else {
+		// Bare repository or inside the git directory itself
+		gitDir = lines[0]
+	}
+
+	if out, err := git("status", "--porcelain=v2", "--branch"); err == nil {
+		parseGitStatus(out, &info)
+		info.HasStatus = true
+	}
+
+	info.State = gitState(gitDir)
+
+	if out, err := git("remote", "-v"); err == nil {
+		info.Remotes = parseGitRemotes(out)
+	}
+
+	if out, err := git("log", "-n", strconv.Itoa(recentCommits), "--format=%h %s"); err == nil && out != "" {
+		info.Commits = strings.Split(out, "\n")
+	}
+
+	return info
+}
+
+// parseGitStatus fills branch and file counts from porcelain v2 output
+func parseGitStatus(out string, info *GitInfo) {
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "#":
+			switch fields[1] {
+			case "branch.oid":
+				if len(fields) > 2 && len(fields[2]) >= 7 {
+					info.Head = fields[2][:7]
+				}
+			case "branch.head":
+				if len(fields) > 2 && fields[2] != "(detached)" {
+					info.Branch = fields[2]
+				}
+			case "branch.upstream":
+				if len(fields) > 2 {
+					info.Upstream = fields[2]
+				}
+			case "branch.ab":
+				if len(fields) > 3 {
+					info.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+					info.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+				}
+			}
+		case "1", "2":
+			// XY: X is the index (staged) state, Y the worktree state
+			xy := fields[1]
+			if len(xy) == 2 {
+				if xy[0] != '.' {
+					info.Staged++
+				}
+				if xy[1] != '.' {
+					info.Unstaged++
+				}
+			}
+		case "u":
+			info.Conflicted++
+		case "?":
+			info.Untracked++
+		}
+	}
+}
+
+// gitState reports an operation in progress in gitDir
+func gitState(gitDir string) string {
+	exists := func(name string) bool {
+		_, err := sys.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge"), exists("rebase-apply"):
+		return "rebase"
+	case exists("MERGE_HEAD"):
+		return "merge"
+	case exists("CHERRY_PICK_HEAD"):
+		return "cherry-pick"
+	case exists("REVERT_HEAD"):
+		return "revert"
+	case exists("BISECT_LOG"):
+		return "bisect"
+	}
+	return ""
+}
+
+// parseGitRemotes parses `git remote -v`, keeping one URL per remote
+func parseGitRemotes(out string) []GitRemote {
+	var remotes []GitRemote
+	seen := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		remotes = append(remotes, GitRemote{Name: fields[0], URL: stripCredentials(fields[1])})
+	}
+	return remotes
+}
+
+// stripCredentials removes any user:token part from a remote URL
+func stripCredentials(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return remote
+	}
+	if _, hasPassword := u.User.Password(); hasPassword || u.Scheme == "https" || u.Scheme == "http" {
+		u.User = nil
+	}
+	return u.String()
+}
+
+// findGitRoot walks up from dir looking for a .git directory or file (as
+// used by worktrees and submodules)
+func findGitRoot(dir string) string {
+	for {
+		if _, err := sys.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
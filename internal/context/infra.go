@@ -0,0 +1,298 @@
+package context
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The collectors in this file only read local config files; none of them
+// talks to a cluster or cloud API.
+
+// KubeInfo is the current kubectl context from kubeconfig
+type KubeInfo struct {
+	Context   string
+	Cluster   string
+	Namespace string
+}
+
+func (d KubeInfo) Facts() []Fact {
+	if d.Context == "" {
+		return nil
+	}
+	return []Fact{{"Kubernetes", fmt.Sprintf("context %s (cluster %s, namespace %s)", d.Context, d.Cluster, d.Namespace)}}
+}
+
+// AWSInfo is the active AWS CLI profile and region
+type AWSInfo struct {
+	Profile string
+	Region  string
+	// EnvCredentials is true when credentials come from AWS_ACCESS_KEY_ID
+	EnvCredentials bool
+}
+
+func (d AWSInfo) Facts() []Fact {
+	if d.Profile == "" && !d.EnvCredentials {
+		return nil
+	}
+	desc := "profile " + d.Profile
+	if d.Profile == "" {
+		desc = "credentials from environment"
+	}
+	if d.Region != "" {
+		desc += ", region " + d.Region
+	}
+	return []Fact{{"AWS", desc}}
+}
+
+// GCloudInfo is the active gcloud configuration
+type GCloudInfo struct {
+	Configuration string
+	Project       string
+	Region        string
+	Zone          string
+}
+
+func (d GCloudInfo) Facts() []Fact {
+	if d.Project == "" {
+		return nil
+	}
+	desc := fmt.Sprintf("project %s (configuration %s)", d.Project, d.Configuration)
+	if d.Region != "" {
+		desc += ", region " + d.Region
+	}
+	if d.Zone != "" {
+		desc += ", zone " + d.Zone
+	}
+	return []Fact{{"Google Cloud", desc}}
+}
+
+// TerraformInfo is the selected workspace of the Terraform root module in
+// the working directory
+type TerraformInfo struct {
+	Workspace string
+}
+
+func (d TerraformInfo) Facts() []Fact {
+	if d.Workspace == "" {
+		return nil
+	}
+	return []Fact{{"Terraform Workspace", d.Workspace}}
+}
+
+func init() {
+	Register(Collector{Name: "kube", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return kubeContext()
+	}})
+
+	Register(Collector{Name: "aws", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return awsProfile(), nil
+	}})
+
+	Register(Collector{Name: "gcloud", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return gcloudConfig(), nil
+	}})
+
+	Register(Collector{Name: "terraform", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		cwd, err := sys.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return terraformWorkspace(cwd), nil
+	}})
+}
+
+// kubeconfig holds the parts of a kubeconfig file we need
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// kubeContext reads the current context from $KUBECONFIG or
+// ~/.kube/config. As in kubectl, the first file to set a value wins.
+func kubeContext() (KubeInfo, error) {
+	var files []string
+	if env := sys.Getenv("KUBECONFIG"); env != "" {
+		files = filepath.SplitList(env)
+	} else if home, err := sys.UserHomeDir(); err == nil {
+		files = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	var configs []kubeconfig
+	info := KubeInfo{}
+	for _, file := range files {
+		data, err := sys.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var kc kubeconfig
+		if err := yaml.Unmarshal(data, &kc); err != nil {
+			return info, fmt.Errorf("%s: %v", file, err)
+		}
+		if info.Context == "" {
+			info.Context = kc.CurrentContext
+		}
+		configs = append(configs, kc)
+	}
+	if info.Context == "" {
+		return info, nil
+	}
+
+	for _, kc := range configs {
+		for _, c := range kc.Contexts {
+			if c.Name == info.Context {
+				info.Cluster = c.Context.Cluster
+				info.Namespace = c.Context.Namespace
+				if info.Namespace == "" {
+					info.Namespace = "default"
+				}
+				return info, nil
+			}
+		}
+	}
+	return info, nil
+}
+
+// awsProfile reads the active profile from the environment and its region
+// from the AWS CLI config file
+func awsProfile() AWSInfo {
+	info := AWSInfo{EnvCredentials: sys.Getenv("AWS_ACCESS_KEY_ID") != ""}
+
+	info.Profile = sys.Getenv("AWS_PROFILE")
+	if info.Profile == "" {
+		info.Profile = sys.Getenv("AWS_DEFAULT_PROFILE")
+	}
+
+	configFile := sys.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		home, err := sys.UserHomeDir()
+		if err != nil {
+			return info
+		}
+		configFile = filepath.Join(home, ".aws", "config")
+	}
+	config := readINI(configFile)
+
+	if info.Profile == "" && config != nil && !info.EnvCredentials {
+		info.Profile = "default"
+	}
+
+	section := "profile " + info.Profile
+	if info.Profile == "default" {
+		section = "default"
+	}
+
+	for _, region := range []string{sys.Getenv("AWS_REGION"), sys.Getenv("AWS_DEFAULT_REGION"), config[section]["region"]} {
+		if region != "" {
+			info.Region = region
+			break
+		}
+	}
+	return info
+}
+
+// gcloudConfig reads the active gcloud configuration from its config
+// directory
+func gcloudConfig() GCloudInfo {
+	dir := sys.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if sys.GOOS() == "windows" {
+			dir = filepath.Join(sys.Getenv("APPDATA"), "gcloud")
+		} else if home, err := sys.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+
+	info := GCloudInfo{Configuration: sys.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")}
+	if info.Configuration == "" {
+		info.Configuration = readTrimmed(filepath.Join(dir, "active_config"))
+	}
+	if info.Configuration == "" {
+		info.Configuration = "default"
+	}
+
+	config := readINI(filepath.Join(dir, "configurations", "config_"+info.Configuration))
+	info.Project = config["core"]["project"]
+	info.Region = config["compute"]["region"]
+	info.Zone = config["compute"]["zone"]
+
+	// Properties set in the environment override the configuration
+	if project := sys.Getenv("CLOUDSDK_CORE_PROJECT"); project != "" {
+		info.Project = project
+	}
+	if region := sys.Getenv("CLOUDSDK_COMPUTE_REGION"); region != "" {
+		info.Region = region
+	}
+	if zone := sys.Getenv("CLOUDSDK_COMPUTE_ZONE"); zone != "" {
+		info.Zone = zone
+	}
+	return info
+}
+
+// terraformWorkspace returns the workspace selected in dir, if dir is an
+// initialised Terraform root module
+func terraformWorkspace(dir string) TerraformInfo {
+	if ws := sys.Getenv("TF_WORKSPACE"); ws != "" {
+		return TerraformInfo{Workspace: ws}
+	}
+
+	dataDir := sys.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+	if !fileExists(dataDir) {
+		return TerraformInfo{}
+	}
+
+	ws := readTrimmed(filepath.Join(dataDir, "environment"))
+	if ws == "" {
+		ws = "default"
+	}
+	return TerraformInfo{Workspace: ws}
+}
+
+// readINI parses a simple INI file into sections of keys, or returns nil
+// if the file cannot be read
+func readINI(path string) map[string]map[string]string {
+	f, err := sys.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	sections := map[string]map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[section] == nil {
+			sections[section] = map[string]string{}
+		}
+		sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections
+}
@@ -0,0 +1,81 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/geoh/how/internal/integration"
+)
+
+// maxStderrLines caps the stderr tail sent with the last command
+const maxStderrLines = 20
+
+// ansiRe matches terminal escape sequences captured along with stderr
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+// LastCommandInfo is the command run just before how, as recorded by the
+// shell integration
+type LastCommandInfo struct {
+	Command  string
+	ExitCode int
+	// Stderr is the tail of the command's stderr when capture is on
+	Stderr string
+}
+
+// Failed reports whether the command exited with a non-zero status
+func (d LastCommandInfo) Failed() bool {
+	return d.ExitCode != 0
+}
+
+func (d LastCommandInfo) Facts() []Fact {
+	facts := []Fact{{"Last Command", fmt.Sprintf("`%s` (exit code %d)", d.Command, d.ExitCode)}}
+	if d.Stderr != "" {
+		facts = append(facts, Fact{"Last Command Stderr", "\n```\n" + d.Stderr + "\n```"})
+	}
+	return facts
+}
+
+// ErrNoLastCommand is returned when the shell integration did not record
+// a previous command
+var ErrNoLastCommand = fmt.Errorf("no previous command recorded; install the shell integration with `how init <shell>`")
+
+func init() {
+	Register(Collector{Name: "last_command", OptIn: true, Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return lastCommand()
+	}})
+}
+
+// lastCommand reads what the shell integration passed in the environment
+func lastCommand() (LastCommandInfo, error) {
+	command := strings.TrimSpace(sys.Getenv(integration.EnvLastCommand))
+	if command == "" {
+		return LastCommandInfo{}, ErrNoLastCommand
+	}
+
+	info := LastCommandInfo{Command: command}
+	info.ExitCode, _ = strconv.Atoi(sys.Getenv(integration.EnvLastStatus))
+
+	if file := sys.Getenv(integration.EnvLastStderr); file != "" {
+		if data, err := sys.ReadFile(file); err == nil {
+			info.Stderr = tailLines(ansiRe.ReplaceAllString(string(data), ""), maxStderrLines)
+		}
+	}
+	return info, nil
+}
+
+// tailLines returns the last n non-empty lines of s
+func tailLines(s string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r", ""), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
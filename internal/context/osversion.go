@@ -0,0 +1,81 @@
+package context
+
+import (
+	gocontext "context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// getOSVersion returns the macOS product name, version and build, the
+// Windows release and version, or the kernel release elsewhere
+func getOSVersion(ctx gocontext.Context) string {
+	var version string
+	switch sys.GOOS() {
+	case "darwin":
+		if out, err := runCommand(ctx, "sw_vers"); err == nil {
+			version = parseSwVers(out)
+		}
+	case "windows":
+		// ver is a cmd builtin, not an executable
+		if out, err := runCommand(ctx, "cmd", "/c", "ver"); err == nil {
+			version = parseWindowsVer(out)
+		}
+	default:
+		version, _ = runCommand(ctx, "uname", "-r")
+	}
+	if version == "" {
+		return "Unknown"
+	}
+	return version
+}
+
+// parseSwVers turns `sw_vers` output into e.g. "macOS 14.4.1 (23E224)"
+func parseSwVers(out string) string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if fields["ProductVersion"] == "" {
+		return ""
+	}
+
+	version := fields["ProductVersion"]
+	if name := fields["ProductName"]; name != "" {
+		version = name + " " + version
+	}
+	if build := fields["BuildVersion"]; build != "" {
+		version += " (" + build + ")"
+	}
+	return version
+}
+
+var winVerRe = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)(?:\.\d+)?`)
+
+// parseWindowsVer turns the output of cmd's `ver` builtin, e.g.
+// "Microsoft Windows [Version 10.0.22631.3296]", into "Windows 11
+// (10.0.22631.3296)"
+func parseWindowsVer(out string) string {
+	m := winVerRe.FindStringSubmatch(out)
+	if m == nil {
+		return ""
+	}
+	major, _ := strconv.Atoi(m[1])
+	build, _ := strconv.Atoi(m[3])
+	return windowsName(major, build) + " (" + m[0] + ")"
+}
+
+// windowsName names a Windows release; Windows 11 still reports version
+// 10.0 and is told apart by its build number
+func windowsName(major, build int) string {
+	switch {
+	case major == 10 && build >= 22000:
+		return "Windows 11"
+	case major == 10:
+		return "Windows 10"
+	default:
+		return "Windows"
+	}
+}
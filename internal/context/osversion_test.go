@@ -0,0 +1,118 @@
+package context
+
+import (
+	gocontext "context"
+	"testing"
+)
+
+func TestParseSwVers(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{
+			name: "sonoma",
+			out:  "ProductName:\t\tmacOS\nProductVersion:\t\t14.4.1\nBuildVersion:\t\t23E224",
+			want: "macOS 14.4.1 (23E224)",
+		},
+		{
+			name: "older macs say Mac OS X",
+			out:  "ProductName:\tMac OS X\nProductVersion:\t10.15.7\nBuildVersion:\t19H2026",
+			want: "Mac OS X 10.15.7 (19H2026)",
+		},
+		{
+			name: "no build",
+			out:  "ProductName: macOS\nProductVersion: 15.0",
+			want: "macOS 15.0",
+		},
+		{
+			name: "no version",
+			out:  "ProductName: macOS",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSwVers(tt.out); got != tt.want {
+				t.Errorf("parseSwVers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindowsVer(t *testing.T) {
+	tests := []struct {
+		out  string
+		want string
+	}{
+		{"\r\nMicrosoft Windows [Version 10.0.22631.3296]\r\n", "Windows 11 (10.0.22631.3296)"},
+		{"Microsoft Windows [Version 10.0.19045.4170]", "Windows 10 (10.0.19045.4170)"},
+		{"Microsoft Windows [Version 10.0.22000.194]", "Windows 11 (10.0.22000.194)"},
+		{"Microsoft Windows [Version 6.3.9600]", "Windows (6.3.9600)"},
+		// Localised builds translate "Version"
+		{"Microsoft Windows [versão 10.0.22631.3447]", "Windows 11 (10.0.22631.3447)"},
+		{"'ver' is not recognized", ""},
+	}
+	for _, tt := range tests {
+		if got := parseWindowsVer(tt.out); got != tt.want {
+			t.Errorf("parseWindowsVer(%q) = %q, want %q", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestGetOSVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		commands map[string]string
+		want     string
+	}{
+		{
+			name:     "macOS",
+			goos:     "darwin",
+			commands: map[string]string{"sw_vers": "ProductName:\tmacOS\nProductVersion:\t14.4.1\nBuildVersion:\t23E224"},
+			want:     "macOS 14.4.1 (23E224)",
+		},
+		{
+			name:     "Windows",
+			goos:     "windows",
+			commands: map[string]string{"cmd /c ver": "Microsoft Windows [Version 10.0.22631.3296]"},
+			want:     "Windows 11 (10.0.22631.3296)",
+		},
+		{
+			name:     "Linux",
+			goos:     "linux",
+			commands: map[string]string{"uname -r": "6.8.0-45-generic"},
+			want:     "6.8.0-45-generic",
+		},
+		{
+			name: "FreeBSD",
+			goos: "freebsd",
+			commands: map[string]string{
+				"uname -r": "14.1-RELEASE",
+				"sw_vers":  "ProductVersion: 14.4",
+			},
+			want: "14.1-RELEASE",
+		},
+		{
+			name: "no sw_vers",
+			goos: "darwin",
+			want: "Unknown",
+		},
+		{
+			name:     "unreadable ver",
+			goos:     "windows",
+			commands: map[string]string{"cmd /c ver": "Access is denied."},
+			want:     "Unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSystem(t, &fakeSystem{goos: tt.goos, commands: tt.commands})
+			if got := getOSVersion(gocontext.Background()); got != tt.want {
+				t.Errorf("getOSVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
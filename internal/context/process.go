@@ -0,0 +1,80 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// processInfo returns the normalised name, executable path and parent of
+// pid: from /proc on Linux, the process table on Windows and ps elsewhere
+func processInfo(ctx gocontext.Context, pid int) (name, path string, ppid int, ok bool) {
+	switch sys.GOOS() {
+	case "linux":
+		return procProcessInfo(pid)
+	case "windows":
+		return snapshotProcessInfo(pid)
+	default:
+		return psProcessInfo(ctx, pid)
+	}
+}
+
+// procProcessInfo reads pid's details from /proc
+func procProcessInfo(pid int) (name, path string, ppid int, ok bool) {
+	stat, err := sys.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", "", 0, false
+	}
+	// The command name is in parentheses and may contain spaces, so parse
+	// from the last ')': state, then ppid
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	if len(fields) < 2 {
+		return "", "", 0, false
+	}
+	ppid, _ = strconv.Atoi(fields[1])
+
+	// exe is accurate where argv[0] and comm are not (login shells show up
+	// as "-bash"), but busybox applets all share one binary
+	path, _ = sys.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	name = shellName(path)
+	if path == "" || name == "busybox" {
+		name = shellName(readTrimmed(fmt.Sprintf("/proc/%d/comm", pid)))
+	}
+	return name, path, ppid, true
+}
+
+// psProcessInfo asks ps for pid's details
+func psProcessInfo(ctx gocontext.Context, pid int) (name, path string, ppid int, ok bool) {
+	out, err := runCommand(ctx, "ps", "-o", "ppid=,comm=", "-p", strconv.Itoa(pid))
+	if err != nil {
+		return "", "", 0, false
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return "", "", 0, false
+	}
+	ppid, _ = strconv.Atoi(fields[0])
+	comm := strings.Join(fields[1:], " ")
+	name = shellName(comm)
+	// Login shells are reported as e.g. "-zsh"
+	if path = strings.TrimPrefix(comm, "-"); !filepath.IsAbs(path) {
+		path, _ = sys.LookPath(name)
+	}
+	return name, path, ppid, true
+}
+
+// defaultShell is used when neither the process tree nor $SHELL names a
+// shell. On Windows it guesses between PowerShell and cmd: PowerShell adds
+// the user's module directory to PSModulePath for its child processes, cmd
+// does not.
+func defaultShell() (name, path string) {
+	if sys.GOOS() != "windows" {
+		return "", ""
+	}
+	if strings.Contains(strings.ToLower(sys.Getenv("PSModulePath")), `\documents\`) {
+		return "powershell", ""
+	}
+	return "cmd", sys.Getenv("ComSpec")
+}
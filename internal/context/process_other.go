@@ -0,0 +1,9 @@
+//go:build !windows
+
+package context
+
+// snapshotProcessInfo needs the Windows API, so a Windows process tree
+// cannot be walked from elsewhere
+func snapshotProcessInfo(pid int) (name, path string, ppid int, ok bool) {
+	return "", "", 0, false
+}
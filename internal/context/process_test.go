@@ -0,0 +1,137 @@
+package context
+
+import (
+	gocontext "context"
+	"testing"
+)
+
+func TestCollectShell(t *testing.T) {
+	tests := []struct {
+		name string
+		sys  *fakeSystem
+		want ShellInfo
+	}{
+		{
+			name: "Linux bash from /proc",
+			sys: &fakeSystem{
+				goos: "linux",
+				ppid: 200,
+				env:  map[string]string{"SHELL": "/bin/bash"},
+				files: map[string]string{
+					"/proc/200/stat": "200 (bash) S 100 200 200 34816 0",
+				},
+				links:    map[string]string{"/proc/200/exe": "/usr/bin/bash"},
+				commands: map[string]string{"/usr/bin/bash --version": "GNU bash, version 5.2.21(1)-release (x86_64-pc-linux-gnu)"},
+			},
+			want: ShellInfo{Name: "bash", Path: "/usr/bin/bash", Version: "5.2.21", Login: "bash"},
+		},
+		{
+			name: "Linux zsh above make",
+			sys: &fakeSystem{
+				goos: "linux",
+				ppid: 300,
+				env:  map[string]string{"SHELL": "/bin/bash"},
+				files: map[string]string{
+					"/proc/300/stat": "300 (make) S 250 300 250 0 0",
+					"/proc/250/stat": "250 (zsh) S 1 250 250 0 0",
+				},
+				links: map[string]string{
+					"/proc/300/exe": "/usr/bin/make",
+					"/proc/250/exe": "/usr/bin/zsh",
+				},
+				commands: map[string]string{"/usr/bin/zsh --version": "zsh 5.9 (x86_64-debian-linux-gnu)"},
+			},
+			want: ShellInfo{Name: "zsh", Path: "/usr/bin/zsh", Version: "5.9", Login: "bash"},
+		},
+		{
+			name: "busybox ash",
+			sys: &fakeSystem{
+				goos: "linux",
+				ppid: 7,
+				files: map[string]string{
+					"/proc/7/stat": "7 (ash) S 1 7 7 0 0",
+					"/proc/7/comm": "ash\n",
+				},
+				links: map[string]string{"/proc/7/exe": "/bin/busybox"},
+			},
+			want: ShellInfo{Name: "ash", Path: "/bin/busybox"},
+		},
+		{
+			name: "macOS login zsh from ps",
+			sys: &fakeSystem{
+				goos:     "darwin",
+				ppid:     812,
+				env:      map[string]string{"SHELL": "/bin/zsh"},
+				path:     map[string]string{"zsh": "/bin/zsh"},
+				commands: map[string]string{"ps -o ppid=,comm= -p 812": "  805 -zsh", "/bin/zsh --version": "zsh 5.9 (x86_64-apple-darwin23.0)"},
+			},
+			want: ShellInfo{Name: "zsh", Path: "/bin/zsh", Version: "5.9", Login: "zsh"},
+		},
+		{
+			name: "macOS Homebrew fish from ps",
+			sys: &fakeSystem{
+				goos: "darwin",
+				ppid: 900,
+				env:  map[string]string{"SHELL": "/bin/zsh"},
+				commands: map[string]string{
+					"ps -o ppid=,comm= -p 900":         "  1 /opt/homebrew/bin/fish",
+					"/opt/homebrew/bin/fish --version": "fish, version 3.7.1",
+				},
+			},
+			want: ShellInfo{Name: "fish", Path: "/opt/homebrew/bin/fish", Version: "3.7.1", Login: "zsh"},
+		},
+		{
+			name: "version from the shell integration",
+			sys: &fakeSystem{
+				goos:     "darwin",
+				ppid:     812,
+				env:      map[string]string{"HOW_SHELL": "zsh", "HOW_SHELL_VERSION": "5.8.1"},
+				path:     map[string]string{"zsh": "/bin/zsh"},
+				commands: map[string]string{"ps -o ppid=,comm= -p 812": "805 zsh"},
+			},
+			want: ShellInfo{Name: "zsh", Path: "/bin/zsh", Version: "5.8.1"},
+		},
+		{
+			name: "Windows PowerShell",
+			sys: &fakeSystem{
+				goos: "windows",
+				env:  map[string]string{"PSModulePath": `C:\Users\ada\Documents\WindowsPowerShell\Modules;C:\Program Files\WindowsPowerShell\Modules`},
+			},
+			want: ShellInfo{Name: "powershell"},
+		},
+		{
+			name: "Windows cmd",
+			sys: &fakeSystem{
+				goos: "windows",
+				env: map[string]string{
+					"PSModulePath": `C:\Program Files\WindowsPowerShell\Modules`,
+					"ComSpec":      `C:\Windows\system32\cmd.exe`,
+				},
+			},
+			want: ShellInfo{Name: "cmd", Path: `C:\Windows\system32\cmd.exe`},
+		},
+		{
+			name: "Windows Git Bash",
+			sys: &fakeSystem{
+				goos:     "windows",
+				env:      map[string]string{"SHELL": `C:\Program Files\Git\usr\bin\bash.exe`},
+				commands: map[string]string{`C:\Program Files\Git\usr\bin\bash.exe --version`: "GNU bash, version 5.2.26(1)-release (x86_64-pc-msys)"},
+			},
+			want: ShellInfo{Name: "bash", Path: `C:\Program Files\Git\usr\bin\bash.exe`, Version: "5.2.26", Login: "bash"},
+		},
+		{
+			name: "nothing to go on",
+			sys:  &fakeSystem{goos: "linux", ppid: 42},
+			want: ShellInfo{Name: "Unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSystem(t, tt.sys)
+			got := collectShell(gocontext.Background())
+			if got.Name != tt.want.Name || got.Path != tt.want.Path || got.Version != tt.want.Version || got.Login != tt.want.Login {
+				t.Errorf("collectShell() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
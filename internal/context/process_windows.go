@@ -0,0 +1,44 @@
+package context
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// snapshotProcessInfo reads pid's details from a snapshot of the process
+// table
+func snapshotProcessInfo(pid int) (name, path string, ppid int, ok bool) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return "", "", 0, false
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		if int(entry.ProcessID) != pid {
+			continue
+		}
+		exe := windows.UTF16ToString(entry.ExeFile[:])
+		return shellName(exe), processPath(pid), int(entry.ParentProcessID), true
+	}
+	return "", "", 0, false
+}
+
+// processPath returns the full executable path of pid, or ""
+func processPath(pid int) string {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:size])
+}
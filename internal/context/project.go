@@ -0,0 +1,397 @@
+package context
+
+import (
+	"bufio"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// maxTargets caps how many scripts or targets are listed per manifest
+const maxTargets = 15
+
+// Project is a build manifest found at or above the working directory
+type Project struct {
+	// Kind is the ecosystem or tool, e.g. "Node", "Go" or "Make"
+	Kind string
+	Dir  string
+	Name string
+	// Manager is the package manager or build tool in use, e.g. "pnpm"
+	Manager string
+	// Targets are scripts, tasks, recipes or services it defines
+	Targets []string
+}
+
+// ProjectInfo lists the manifests of the surrounding project, nearest first
+type ProjectInfo struct {
+	Projects []Project
+}
+
+func (d ProjectInfo) Facts() []Fact {
+	var facts []Fact
+	for _, p := range d.Projects {
+		facts = append(facts, Fact{"Project", p.summary()})
+	}
+	return facts
+}
+
+// summary renders a project as e.g.
+// "Node project using pnpm; scripts: build, test (in /src/app)"
+func (p Project) summary() string {
+	var b strings.Builder
+	b.WriteString(p.Kind)
+	b.WriteString(" project")
+	if p.Name != "" {
+		fmt.Fprintf(&b, " %s", p.Name)
+	}
+	if p.Manager != "" {
+		fmt.Fprintf(&b, " using %s", p.Manager)
+	}
+	if len(p.Targets) > 0 {
+		fmt.Fprintf(&b, "; %s: %s", targetNoun(p.Kind), strings.Join(p.Targets, ", "))
+	}
+	fmt.Fprintf(&b, " (in %s)", p.Dir)
+	return b.String()
+}
+
+// targetNoun names what a project's targets are called
+func targetNoun(kind string) string {
+	switch kind {
+	case "Make":
+		return "targets"
+	case "just":
+		return "recipes"
+	case "Task":
+		return "tasks"
+	case "Docker Compose":
+		return "services"
+	}
+	return "scripts"
+}
+
+// manifestDetector recognises one kind of manifest in a directory
+type manifestDetector struct {
+	kind   string
+	detect func(dir string) (Project, bool)
+}
+
+var manifestDetectors = []manifestDetector{
+	{"Go", detectGo},
+	{"Node", detectNode},
+	{"Python", detectPython},
+	{"Rust", detectRust},
+	{"Java", detectJava},
+	{"Make", detectMake},
+	{"just", detectJust},
+	{"Task", detectTask},
+	{"Docker Compose", detectCompose},
+}
+
+func init() {
+	Register(Collector{Name: "project", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		cwd, err := sys.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return detectProjects(ctx, cwd), nil
+	}})
+}
+
+// detectProjects walks up from dir to the repository root (or the home
+// directory) and reports the nearest manifest of each kind
+func detectProjects(ctx gocontext.Context, dir string) ProjectInfo {
+	var info ProjectInfo
+	found := map[string]bool{}
+	home, _ := sys.UserHomeDir()
+	nodeIdx := -1
+
+	for {
+		// A package inside a monorepo has no lockfile of its own; the
+		// workspace root further up decides the package manager
+		if nodeIdx != -1 && info.Projects[nodeIdx].Manager == "" {
+			if manager := nodeLockManager(dir); manager != "" {
+				info.Projects[nodeIdx].Manager = fmt.Sprintf("%s (workspace root %s)", manager, dir)
+			}
+		}
+
+		for _, d := range manifestDetectors {
+			if found[d.kind] || ctx.Err() != nil {
+				continue
+			}
+			if p, ok := d.detect(dir); ok {
+				p.Kind = d.kind
+				p.Dir = dir
+				if len(p.Targets) > maxTargets {
+					p.Targets = append(p.Targets[:maxTargets], "...")
+				}
+				if d.kind == "Node" {
+					nodeIdx = len(info.Projects)
+				}
+				info.Projects = append(info.Projects, p)
+				found[d.kind] = true
+			}
+		}
+
+		// Stop at the repository root, the home directory or /
+		if fileExists(filepath.Join(dir, ".git")) || dir == home {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return info
+}
+
+func detectGo(dir string) (Project, bool) {
+	f, err := sys.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return Project{}, false
+	}
+	defer f.Close()
+
+	p := Project{Manager: "go modules"}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			p.Name = fields[1]
+		}
+		if len(fields) == 2 && fields[0] == "go" {
+			p.Manager = "go " + fields[1]
+		}
+	}
+	if fileExists(filepath.Join(dir, "go.work")) {
+		p.Manager += " (workspace)"
+	}
+	return p, true
+}
+
+func detectNode(dir string) (Project, bool) {
+	data, err := sys.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return Project{}, false
+	}
+
+	var pkg struct {
+		Name           string            `json:"name"`
+		PackageManager string            `json:"packageManager"`
+		Scripts        map[string]string `json:"scripts"`
+		Workspaces     json.RawMessage   `json:"workspaces"`
+	}
+	json.Unmarshal(data, &pkg)
+
+	p := Project{Name: pkg.Name, Targets: sortedKeys(pkg.Scripts)}
+
+	// The lockfile says what is actually used; packageManager is a hint
+	p.Manager = nodeLockManager(dir)
+	if p.Manager == "" && pkg.PackageManager != "" {
+		p.Manager, _, _ = strings.Cut(pkg.PackageManager, "@")
+	}
+
+	if len(pkg.Workspaces) > 0 || fileExists(filepath.Join(dir, "pnpm-workspace.yaml")) {
+		p.Manager = strings.TrimSpace(p.Manager + " workspaces")
+	}
+	return p, true
+}
+
+// nodeLockManager names the package manager whose lockfile is in dir
+func nodeLockManager(dir string) string {
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		return "pnpm"
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		return "yarn"
+	case fileExists(filepath.Join(dir, "bun.lockb")), fileExists(filepath.Join(dir, "bun.lock")):
+		return "bun"
+	case fileExists(filepath.Join(dir, "package-lock.json")):
+		return "npm"
+	}
+	return ""
+}
+
+func detectPython(dir string) (Project, bool) {
+	var p Project
+	hasPyproject := false
+
+	if data, err := sys.ReadFile(filepath.Join(dir, "pyproject.toml")); err == nil {
+		hasPyproject = true
+		var pyproject struct {
+			Project struct {
+				Name    string            `toml:"name"`
+				Scripts map[string]string `toml:"scripts"`
+			} `toml:"project"`
+			Tool map[string]any `toml:"tool"`
+		}
+		toml.Decode(string(data), &pyproject)
+		p.Name = pyproject.Project.Name
+		p.Targets = sortedKeys(pyproject.Project.Scripts)
+		if _, ok := pyproject.Tool["poetry"]; ok {
+			p.Manager = "poetry"
+		}
+		if _, ok := pyproject.Tool["pdm"]; ok {
+			p.Manager = "pdm"
+		}
+		if _, ok := pyproject.Tool["hatch"]; ok && p.Manager == "" {
+			p.Manager = "hatch"
+		}
+	}
+
+	switch {
+	case fileExists(filepath.Join(dir, "uv.lock")):
+		p.Manager = "uv"
+	case fileExists(filepath.Join(dir, "poetry.lock")):
+		p.Manager = "poetry"
+	case fileExists(filepath.Join(dir, "pdm.lock")):
+		p.Manager = "pdm"
+	case fileExists(filepath.Join(dir, "Pipfile")):
+		p.Manager = "pipenv"
+	case fileExists(filepath.Join(dir, "requirements.txt")):
+		if p.Manager == "" {
+			p.Manager = "pip (requirements.txt)"
+		}
+	case !hasPyproject && !fileExists(filepath.Join(dir, "setup.py")):
+		return Project{}, false
+	}
+	return p, true
+}
+
+func detectRust(dir string) (Project, bool) {
+	data, err := sys.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return Project{}, false
+	}
+
+	var cargo struct {
+		Package struct {
+			Name string `toml:"name"`
+		} `toml:"package"`
+		Workspace *struct{} `toml:"workspace"`
+	}
+	toml.Decode(string(data), &cargo)
+
+	p := Project{Name: cargo.Package.Name, Manager: "cargo"}
+	if cargo.Workspace != nil {
+		p.Manager = "cargo workspace"
+	}
+	return p, true
+}
+
+func detectJava(dir string) (Project, bool) {
+	switch {
+	case fileExists(filepath.Join(dir, "pom.xml")):
+		if fileExists(filepath.Join(dir, "mvnw")) {
+			return Project{Manager: "Maven (./mvnw)"}, true
+		}
+		return Project{Manager: "Maven"}, true
+	case fileExists(filepath.Join(dir, "build.gradle")), fileExists(filepath.Join(dir, "build.gradle.kts")):
+		if fileExists(filepath.Join(dir, "gradlew")) {
+			return Project{Manager: "Gradle (./gradlew)"}, true
+		}
+		return Project{Manager: "Gradle"}, true
+	}
+	return Project{}, false
+}
+
+var makeTargetRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*)\s*:([^=]|$)`)
+
+func detectMake(dir string) (Project, bool) {
+	for _, name := range []string{"GNUmakefile", "Makefile", "makefile"} {
+		if targets, ok := scanTargets(filepath.Join(dir, name), makeTargetRe); ok {
+			return Project{Targets: targets}, true
+		}
+	}
+	return Project{}, false
+}
+
+var justRecipeRe = regexp.MustCompile(`^@?([A-Za-z0-9_-]+)[^:=]*:([^=]|$)`)
+
+func detectJust(dir string) (Project, bool) {
+	for _, name := range []string{"justfile", "Justfile", ".justfile"} {
+		if recipes, ok := scanTargets(filepath.Join(dir, name), justRecipeRe); ok {
+			return Project{Targets: recipes}, true
+		}
+	}
+	return Project{}, false
+}
+
+func detectTask(dir string) (Project, bool) {
+	for _, name := range []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"} {
+		if tasks, ok := yamlSectionKeys(filepath.Join(dir, name), "tasks"); ok {
+			return Project{Targets: tasks}, true
+		}
+	}
+	return Project{}, false
+}
+
+func detectCompose(dir string) (Project, bool) {
+	for _, name := range []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"} {
+		if services, ok := yamlSectionKeys(filepath.Join(dir, name), "services"); ok {
+			return Project{Targets: services}, true
+		}
+	}
+	return Project{}, false
+}
+
+// scanTargets returns the first capture group of re for every unindented
+// line of path
+func scanTargets(path string, re *regexp.Regexp) ([]string, bool) {
+	f, err := sys.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var targets []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := re.FindStringSubmatch(scanner.Text())
+		if m == nil || seen[m[1]] || strings.HasPrefix(m[1], ".") {
+			continue
+		}
+		seen[m[1]] = true
+		targets = append(targets, m[1])
+	}
+	return targets, true
+}
+
+// yamlSectionKeys returns the keys of a top-level mapping in the YAML file
+// at path, such as the tasks of a Taskfile
+func yamlSectionKeys(path, section string) ([]string, bool) {
+	data, err := sys.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var doc map[string]any
+	yaml.Unmarshal(data, &doc)
+	entries, _ := doc[section].(map[string]any)
+	return sortedKeys(entries), true
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := sys.Stat(path)
+	return err == nil
+}
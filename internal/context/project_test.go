@@ -0,0 +1,51 @@
+package context
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestYAMLSectionKeys(t *testing.T) {
+	useSystem(t, &fakeSystem{files: map[string]string{
+		"/repo/Taskfile.yml": `version: '3'
+vars:
+  GREETING: hello
+tasks:
+  # builds the binary
+  build:
+    cmds:
+      - go build ./...
+  "test":
+    cmds:
+      - |
+        go test ./...
+        lint: not a task
+  lint: {cmds: [golangci-lint run]}
+`,
+		"/repo/compose.yaml": `x-base: &base
+  restart: always
+services: {web: {<<: *base, image: nginx}, db: {image: postgres}}
+`,
+		"/broken/Taskfile.yml": "tasks: [\n",
+	}})
+
+	tests := []struct {
+		dir    string
+		detect func(string) (Project, bool)
+		want   []string
+	}{
+		{"/repo", detectTask, []string{"build", "lint", "test"}},
+		{"/repo", detectCompose, []string{"db", "web"}},
+		{"/broken", detectTask, nil},
+	}
+	for _, tt := range tests {
+		p, ok := tt.detect(tt.dir)
+		if !ok {
+			t.Errorf("no project detected in %s", tt.dir)
+			continue
+		}
+		if !slices.Equal(p.Targets, tt.want) {
+			t.Errorf("targets in %s = %q, want %q", tt.dir, p.Targets, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/geoh/how/internal/platform"
+)
+
+// RuntimeInfo describes where how is running: containers, VMs, remote
+// sessions and activated language environments
+type RuntimeInfo struct {
+	// Container is e.g. "docker", "podman", "lxc" or "containerd"
+	Container  string
+	Kubernetes bool
+	// VM is the hypervisor or cloud reported by the firmware, if any
+	VM           string
+	WSL          bool
+	Devcontainer bool
+	SSH          bool
+	// Init is the name of PID 1; Systemd is true when it is running
+	Init    string
+	Systemd bool
+	// NixShell is "pure" or "impure" inside nix-shell / nix develop
+	NixShell   string
+	VirtualEnv string
+	CondaEnv   string
+}
+
+func (d RuntimeInfo) Facts() []Fact {
+	var parts []string
+	if d.Container != "" {
+		parts = append(parts, d.Container+" container")
+	}
+	if d.Kubernetes {
+		parts = append(parts, "Kubernetes pod")
+	}
+	if d.Devcontainer {
+		parts = append(parts, "devcontainer")
+	}
+	if d.WSL {
+		parts = append(parts, "WSL")
+	}
+	if d.VM != "" {
+		parts = append(parts, "VM ("+d.VM+")")
+	}
+	if d.SSH {
+		parts = append(parts, "SSH session")
+	}
+	// Init is only known on Linux
+	switch {
+	case d.Systemd:
+		parts = append(parts, "systemd")
+	case d.Init != "":
+		parts = append(parts, fmt.Sprintf("no systemd (PID 1: %s)", d.Init))
+	}
+
+	var facts []Fact
+	if len(parts) > 0 {
+		facts = append(facts, Fact{"Runtime", strings.Join(parts, ", ")})
+	}
+
+	var envs []string
+	if d.NixShell != "" {
+		envs = append(envs, fmt.Sprintf("Nix shell (%s)", d.NixShell))
+	}
+	if d.VirtualEnv != "" {
+		envs = append(envs, fmt.Sprintf("Python venv (%s)", d.VirtualEnv))
+	}
+	if d.CondaEnv != "" {
+		envs = append(envs, fmt.Sprintf("conda env %s", d.CondaEnv))
+	}
+	if len(envs) > 0 {
+		facts = append(facts, Fact{"Active Environments", strings.Join(envs, ", ")})
+	}
+
+	return facts
+}
+
+func init() {
+	Register(Collector{Name: "runtime", Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		return RuntimeInfo{
+			Container:    detectContainer(),
+			Kubernetes:   sys.Getenv("KUBERNETES_SERVICE_HOST") != "" || fileExists("/var/run/secrets/kubernetes.io/serviceaccount"),
+			VM:           detectVM(),
+			WSL:          platform.DetectWSL(sys.Getenv, sys.ReadFile),
+			Devcontainer: sys.Getenv("REMOTE_CONTAINERS") != "" || sys.Getenv("CODESPACES") != "" || sys.Getenv("DEVCONTAINER") != "",
+			SSH:          platform.DetectSSH(sys.Getenv),
+			Init:         readTrimmed("/proc/1/comm"),
+			Systemd:      fileExists("/run/systemd/system"),
+			NixShell:     sys.Getenv("IN_NIX_SHELL"),
+			VirtualEnv:   sys.Getenv("VIRTUAL_ENV"),
+			CondaEnv:     sys.Getenv("CONDA_DEFAULT_ENV"),
+		}, nil
+	}})
+}
+
+// detectContainer names the container runtime we are running under, or
+// returns "" outside a container
+func detectContainer() string {
+	if fileExists("/.dockerenv") {
+		return "docker"
+	}
+	if fileExists("/run/.containerenv") {
+		return "podman"
+	}
+	// Set by systemd-nspawn, podman and LXC
+	if c := sys.Getenv("container"); c != "" {
+		return c
+	}
+
+	cgroup := strings.ToLower(readTrimmed("/proc/1/cgroup"))
+	for _, name := range []string{"docker", "kubepods", "containerd", "lxc", "podman"} {
+		if strings.Contains(cgroup, name) {
+			if name == "kubepods" {
+				return "containerd"
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// vmVendors maps firmware vendor or product strings to a hypervisor name,
+// most specific first
+var vmVendors = [][2]string{
+	{"amazon ec2", "Amazon EC2"},
+	{"google compute engine", "Google Compute Engine"},
+	{"microsoft corporation", "Hyper-V"},
+	{"vmware", "VMware"},
+	{"virtualbox", "VirtualBox"},
+	{"innotek", "VirtualBox"},
+	{"parallels", "Parallels"},
+	{"qemu", "QEMU/KVM"},
+	{"kvm", "KVM"},
+	{"xen", "Xen"},
+}
+
+// detectVM checks the DMI strings exposed by Linux for a known hypervisor
+func detectVM() string {
+	if sys.GOOS() != "linux" {
+		return ""
+	}
+	var dmi []string
+	for _, name := range []string{"sys_vendor", "product_name"} {
+		dmi = append(dmi, strings.ToLower(readTrimmed(filepath.Join("/sys/class/dmi/id", name))))
+	}
+	joined := strings.Join(dmi, " ")
+	for _, vendor := range vmVendors {
+		if strings.Contains(joined, vendor[0]) {
+			return vendor[1]
+		}
+	}
+	return ""
+}
+
+// readTrimmed returns the trimmed contents of path, or "" if unreadable
+func readTrimmed(path string) string {
+	data, err := sys.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,192 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/geoh/how/internal/integration"
+)
+
+const (
+	// maxShellDepth bounds the walk up the process tree, which passes
+	// through wrappers such as sudo, make or npm before reaching the shell
+	maxShellDepth = 8
+	// maxAliases and maxFunctions cap what the shell integration reports
+	maxAliases   = 50
+	maxFunctions = 50
+	// maxAliasValue truncates long alias definitions
+	maxAliasValue = 60
+)
+
+// knownShells are process names recognised as interactive shells
+var knownShells = map[string]bool{
+	"bash": true, "zsh": true, "fish": true, "sh": true, "dash": true,
+	"ash": true, "ksh": true, "mksh": true, "ksh93": true, "tcsh": true,
+	"csh": true, "nu": true, "pwsh": true, "powershell": true, "cmd": true,
+	"xonsh": true, "elvish": true, "osh": true, "ysh": true,
+}
+
+// shellVersionArgs holds the version flag of shells that have one
+var shellVersionArgs = map[string][]string{
+	"bash":   {"--version"},
+	"zsh":    {"--version"},
+	"fish":   {"--version"},
+	"tcsh":   {"--version"},
+	"nu":     {"--version"},
+	"pwsh":   {"--version"},
+	"xonsh":  {"--version"},
+	"elvish": {"-version"},
+	// Windows PowerShell 5.1 has no version flag
+	"powershell": {"-NoProfile", "-Command", "$PSVersionTable.PSVersion.ToString()"},
+}
+
+// ShellInfo describes the shell how was started from
+type ShellInfo struct {
+	// Name is the running shell, e.g. "fish", found by walking up the
+	// process tree
+	Name    string
+	Path    string
+	Version string
+	// Login is the login shell from $SHELL, which may differ from Name
+	Login string
+
+	// Aliases ("name='value'") and Functions are only known when the
+	// shell integration is installed
+	Aliases          []string
+	Functions        []string
+	AliasesTruncated bool
+}
+
+func (d ShellInfo) Facts() []Fact {
+	shell := d.Name
+	if d.Version != "" {
+		shell += " " + d.Version
+	}
+	if d.Login != "" && d.Login != d.Name {
+		shell += fmt.Sprintf(" (login shell: %s)", d.Login)
+	}
+	facts := []Fact{{"Shell", shell}}
+
+	if len(d.Aliases) > 0 {
+		aliases := strings.Join(d.Aliases, ", ")
+		if d.AliasesTruncated {
+			aliases += ", ..."
+		}
+		facts = append(facts, Fact{"Shell Aliases", aliases})
+	}
+	if len(d.Functions) > 0 {
+		facts = append(facts, Fact{"Shell Functions", strings.Join(d.Functions, ", ")})
+	}
+	return facts
+}
+
+// collectShell identifies the running shell, its version and, through the
+// shell integration, the user's aliases and functions
+func collectShell(ctx gocontext.Context) ShellInfo {
+	info := ShellInfo{}
+	if shell := sys.Getenv("SHELL"); shell != "" {
+		info.Login = shellName(shell)
+	}
+
+	info.Name, info.Path = parentShell(ctx)
+	if info.Name == "" {
+		// Fall back to the login shell
+		info.Name, info.Path = info.Login, sys.Getenv("SHELL")
+	}
+	if info.Name == "" {
+		info.Name, info.Path = defaultShell()
+	}
+	if info.Name == "" {
+		info.Name = "Unknown"
+		return info
+	}
+
+	// The integration knows the version without running the shell
+	if sys.Getenv(integration.EnvShell) == info.Name {
+		info.Version = versionRe.FindString(sys.Getenv(integration.EnvShellVersion))
+	}
+	if args, ok := shellVersionArgs[info.Name]; ok && info.Version == "" && info.Path != "" {
+		info.Version = runVersion(ctx, info.Path, args)
+	}
+
+	info.Aliases, info.AliasesTruncated = parseAliases(sys.Getenv(integration.EnvAliases))
+	info.Functions = parseFunctions(sys.Getenv(integration.EnvFunctions))
+	return info
+}
+
+// parentShell walks up from the parent process to the first known shell
+func parentShell(ctx gocontext.Context) (name, path string) {
+	pid := sys.Getppid()
+	for depth := 0; depth < maxShellDepth && pid > 1; depth++ {
+		name, path, ppid, ok := processInfo(ctx, pid)
+		if !ok {
+			return "", ""
+		}
+		if knownShells[name] {
+			return name, path
+		}
+		pid = ppid
+	}
+	return "", ""
+}
+
+// shellName reduces a path or process name such as "-zsh" or
+// "C:\...\pwsh.exe" to the shell's name
+func shellName(s string) string {
+	s = path.Base(strings.ReplaceAll(strings.TrimPrefix(s, "-"), `\`, "/"))
+	return strings.TrimSuffix(strings.ToLower(s), ".exe")
+}
+
+// parseAliases reads `alias` output from bash ("alias ll='ls -l'"), zsh
+// ("ll='ls -l'") or fish ("alias ll 'ls -l'")
+func parseAliases(out string) ([]string, bool) {
+	var aliases []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "alias ")
+		i := strings.IndexAny(line, "= ")
+		if i <= 0 {
+			continue
+		}
+		if len(aliases) == maxAliases {
+			return aliases, true
+		}
+
+		name, value := line[:i], strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `'\''`, "'")
+		}
+		if len(value) > maxAliasValue {
+			value = value[:maxAliasValue] + "..."
+		}
+		aliases = append(aliases, fmt.Sprintf("%s='%s'", name, value))
+	}
+	return aliases, false
+}
+
+// parseFunctions reads one function name per line, skipping private and
+// built-in helpers, the integration's own wrapper and helpers of another
+// function such as nvm_download next to nvm
+func parseFunctions(out string) []string {
+	names := strings.Fields(out)
+	defined := map[string]bool{}
+	for _, name := range names {
+		defined[name] = true
+	}
+
+	var functions []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, "fish_") || name == "how" {
+			continue
+		}
+		if i := strings.IndexByte(name, '_'); i > 0 && defined[name[:i]] {
+			continue
+		}
+		if len(functions) == maxFunctions {
+			break
+		}
+		functions = append(functions, name)
+	}
+	return functions
+}
@@ -0,0 +1,288 @@
+package context
+
+import (
+	gocontext "context"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/geoh/how/internal/integration"
+)
+
+const (
+	// DefaultHistoryEntries is how many history entries are sent
+	DefaultHistoryEntries = 10
+	// historyTailBytes is how much of the end of a history file is read
+	historyTailBytes = 64 * 1024
+)
+
+// ShellHistoryInfo holds the most recent commands from the shell history
+type ShellHistoryInfo struct {
+	Shell   string
+	Entries []string
+}
+
+func (d ShellHistoryInfo) Facts() []Fact {
+	if len(d.Entries) == 0 {
+		return nil
+	}
+	return []Fact{{"Recent Shell History", "\n```\n" + strings.Join(d.Entries, "\n") + "\n```"}}
+}
+
+func init() {
+	Register(Collector{Name: "shell_history", OptIn: true, Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		n := opts.HistoryEntries
+		if n <= 0 {
+			n = DefaultHistoryEntries
+		}
+		return shellHistory(ctx, n)
+	}})
+}
+
+// shellHistory returns the last n commands, preferring the in-memory
+// history passed by the shell integration, which includes commands of the
+// current session not yet written to HISTFILE
+func shellHistory(ctx gocontext.Context, n int) (ShellHistoryInfo, error) {
+	shell := sys.Getenv(integration.EnvShell)
+	if shell == "" {
+		shell, _ = parentShell(ctx)
+	}
+	if shell == "" {
+		shell = shellName(sys.Getenv("SHELL"))
+	}
+	info := ShellHistoryInfo{Shell: shell}
+
+	var entries []string
+	if recent := sys.Getenv(integration.EnvHistory); recent != "" {
+		entries = parseHistoryOutput(recent)
+	} else {
+		file := historyFile(shell)
+		if file == "" {
+			return info, nil
+		}
+		data, err := readTail(file, historyTailBytes)
+		if err != nil {
+			return info, err
+		}
+		switch shell {
+		case "zsh":
+			entries = parseZshHistory(data)
+		case "fish":
+			entries = parseFishHistory(data)
+		default:
+			entries = parseBashHistory(data)
+		}
+	}
+
+	info.Entries = lastEntries(entries, n)
+	return info, nil
+}
+
+// historyFile finds the history file of shell
+func historyFile(shell string) string {
+	if file := sys.Getenv(integration.EnvHistFile); file != "" {
+		return file
+	}
+	if file := sys.Getenv("HISTFILE"); file != "" {
+		return file
+	}
+	home, err := sys.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	switch shell {
+	case "bash", "sh":
+		return filepath.Join(home, ".bash_history")
+	case "zsh":
+		dir := sys.Getenv("ZDOTDIR")
+		if dir == "" {
+			dir = home
+		}
+		for _, name := range []string{".zsh_history", ".zhistory", ".histfile"} {
+			if file := filepath.Join(dir, name); fileExists(file) {
+				return file
+			}
+		}
+	case "fish":
+		dir := sys.Getenv("XDG_DATA_HOME")
+		if dir == "" {
+			dir = filepath.Join(home, ".local", "share")
+		}
+		session := sys.Getenv("fish_history")
+		if session == "" {
+			session = "fish"
+		}
+		return filepath.Join(dir, "fish", session+"_history")
+	}
+	return ""
+}
+
+// readTail reads up to n bytes from the end of file, dropping a partial
+// first line
+func readTail(file string, n int64) (string, error) {
+	f, err := sys.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := info.Size() - n
+	if seeker, ok := f.(io.Seeker); !ok || offset < 0 {
+		offset = 0
+	} else if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	s := string(data)
+	if offset > 0 {
+		if i := strings.IndexByte(s, '\n'); i != -1 {
+			s = s[i+1:]
+		}
+	}
+	return s, nil
+}
+
+// historyLineRe matches the event number of `history` and `fc -l` lines,
+// "%5d%c " with '*' marking modified entries, leaving the command's own
+// leading space in place
+var historyLineRe = regexp.MustCompile(`^\s*\d+[* ] `)
+
+// parseHistoryOutput parses numbered `history` or `fc -l` output from the
+// shell integration; lines without a number continue a multi-line command
+func parseHistoryOutput(data string) []string {
+	var entries []string
+	for _, line := range strings.Split(data, "\n") {
+		if loc := historyLineRe.FindStringIndex(line); loc != nil {
+			entries = append(entries, line[loc[1]:])
+		} else if len(entries) > 0 {
+			entries[len(entries)-1] += "\n" + line
+		}
+	}
+	return entries
+}
+
+// parseBashHistory parses a bash history file, skipping the "#<epoch>"
+// lines written when HISTTIMEFORMAT is set
+func parseBashHistory(data string) []string {
+	var entries []string
+	for _, line := range strings.Split(data, "\n") {
+		if len(line) > 1 && line[0] == '#' && strings.Trim(line[1:], "0123456789") == "" {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries
+}
+
+// parseZshHistory parses a zsh history file in plain or extended
+// (": <start>:<elapsed>;<command>") format. Multi-line commands end their
+// lines with a backslash.
+func parseZshHistory(data string) []string {
+	data = unmetafy(data)
+	var entries []string
+	var current strings.Builder
+	for _, line := range strings.Split(data, "\n") {
+		if current.Len() == 0 && strings.HasPrefix(line, ": ") {
+			if i := strings.IndexByte(line, ';'); i != -1 {
+				line = line[i+1:]
+			}
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\") + "\n")
+			continue
+		}
+		current.WriteString(line)
+		entries = append(entries, current.String())
+		current.Reset()
+	}
+	return entries
+}
+
+// unmetafy undoes zsh's escaping of special bytes in history files: 0x83
+// followed by a byte XORed with 32
+func unmetafy(s string) string {
+	if strings.IndexByte(s, 0x83) == -1 {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x83 && i+1 < len(s) {
+			i++
+			out = append(out, s[i]^32)
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// parseFishHistory parses fish's YAML-like history ("- cmd: ..." entries)
+func parseFishHistory(data string) []string {
+	var entries []string
+	for _, line := range strings.Split(data, "\n") {
+		if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			cmd = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(cmd)
+			entries = append(entries, cmd)
+		}
+	}
+	return entries
+}
+
+// lastEntries returns the last n entries worth sending. Like
+// HISTCONTROL=ignorespace, commands starting with a space are private;
+// commands matching HISTIGNORE and how's own invocations are skipped too.
+func lastEntries(entries []string, n int) []string {
+	var ignore []string
+	if h := sys.Getenv("HISTIGNORE"); h != "" {
+		ignore = strings.Split(h, ":")
+	}
+
+	var kept []string
+	for i := len(entries) - 1; i >= 0 && len(kept) < n; i-- {
+		entry := entries[i]
+		if strings.TrimSpace(entry) == "" || strings.HasPrefix(entry, " ") {
+			continue
+		}
+		if fields := strings.Fields(entry); fields[0] == "how" {
+			continue
+		}
+		if matchesAny(ignore, entry) {
+			continue
+		}
+		if len(kept) > 0 && kept[len(kept)-1] == entry {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	// Oldest first
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	return kept
+}
+
+// matchesAny reports whether s matches one of the HISTIGNORE-style glob
+// patterns, where * also matches slashes
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if p == "" || p == "&" {
+			continue
+		}
+		glob := regexp.QuoteMeta(p)
+		glob = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(glob)
+		if ok, _ := regexp.MatchString("^"+glob+"$", s); ok {
+			return true
+		}
+	}
+	return false
+}
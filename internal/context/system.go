@@ -0,0 +1,111 @@
+package context
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The collectors reach the machine they describe only through sys, so a
+// fake can simulate another environment: a container without git, a
+// different shell, a home directory full of cloud config.
+var sys system = hostSystem{}
+
+type system interface {
+	runner
+	environment
+	filesystem
+}
+
+// runner runs external commands
+type runner interface {
+	// Run returns the trimmed output of cmd. When the command fails the
+	// output is still returned, with an error that has an ExitCode method
+	// if the command ran at all.
+	Run(ctx gocontext.Context, cmd command) (string, error)
+	LookPath(file string) (string, error)
+}
+
+// command describes a command for a runner
+type command struct {
+	Name string
+	Args []string
+	// Dir is the working directory, "" for the current one
+	Dir   string
+	Stdin string
+	// Stderr adds standard error to the output
+	Stderr bool
+}
+
+// environment is the process environment
+type environment interface {
+	// GOOS is the operating system, as in runtime.GOOS
+	GOOS() string
+	Getenv(key string) string
+	Environ() []string
+	Getwd() (string, error)
+	UserHomeDir() (string, error)
+	Getppid() int
+}
+
+// filesystem is read-only access to files by absolute path
+type filesystem interface {
+	Open(name string) (fs.File, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+}
+
+// hostSystem is the real machine
+type hostSystem struct{}
+
+func (hostSystem) Run(ctx gocontext.Context, c command) (string, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	if c.Stdin != "" {
+		cmd.Stdin = strings.NewReader(c.Stdin)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if c.Stderr {
+		cmd.Stderr = &out
+	}
+	err := cmd.Run()
+	return strings.TrimSpace(out.String()), err
+}
+
+func (hostSystem) LookPath(file string) (string, error)       { return exec.LookPath(file) }
+func (hostSystem) GOOS() string                               { return runtime.GOOS }
+func (hostSystem) Getenv(key string) string                   { return os.Getenv(key) }
+func (hostSystem) Environ() []string                          { return os.Environ() }
+func (hostSystem) Getwd() (string, error)                     { return os.Getwd() }
+func (hostSystem) UserHomeDir() (string, error)               { return os.UserHomeDir() }
+func (hostSystem) Getppid() int                               { return os.Getppid() }
+func (hostSystem) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (hostSystem) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (hostSystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (hostSystem) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (hostSystem) Readlink(name string) (string, error)       { return os.Readlink(name) }
+
+// runCommand runs name with args in the current directory and returns its
+// trimmed stdout
+func runCommand(ctx gocontext.Context, name string, args ...string) (string, error) {
+	return sys.Run(ctx, command{Name: name, Args: args})
+}
+
+// exitCode returns the exit status carried by a runner error, or -1 when
+// the command did not run
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(interface{ ExitCode() int }); ok {
+		return e.ExitCode()
+	}
+	return -1
+}
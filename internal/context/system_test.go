@@ -0,0 +1,141 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeSystem simulates a machine for the collectors: its OS, environment,
+// files and the output of the commands they run
+type fakeSystem struct {
+	goos string
+	env  map[string]string
+	cwd  string
+	home string
+	ppid int
+	// files maps absolute paths to their contents; directories are implied
+	files map[string]string
+	// links maps symlinks, such as /proc/<pid>/exe, to their targets
+	links map[string]string
+	// path maps program names to where they are installed
+	path map[string]string
+	// commands maps a command line, e.g. "git rev-parse HEAD", to its
+	// output; exits holds the status of command lines that fail
+	commands map[string]string
+	exits    map[string]int
+	// hangs holds command lines that run until their context ends
+	hangs map[string]bool
+
+	// t is the test using the system, set by useSystem
+	t *testing.T
+}
+
+// useSystem makes the collectors see s for the rest of the test
+func useSystem(t *testing.T, s *fakeSystem) {
+	t.Helper()
+	s.t = t
+	old := sys
+	sys = s
+	t.Cleanup(func() { sys = old })
+}
+
+// fakeExit is the error of a command that ran and failed
+type fakeExit int
+
+func (e fakeExit) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e fakeExit) ExitCode() int { return int(e) }
+
+func (s *fakeSystem) Run(ctx gocontext.Context, c command) (string, error) {
+	line := strings.Join(append([]string{c.Name}, c.Args...), " ")
+	if s.hangs[line] {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	out, ok := s.commands[line]
+	if code, failed := s.exits[line]; failed {
+		return out, fakeExit(code)
+	}
+	if !ok {
+		// Programs that are not installed fail as they would with exec;
+		// an installed one the fixture has no output for is a gap in it
+		if _, err := s.LookPath(c.Name); err != nil && !filepath.IsAbs(c.Name) {
+			return "", err
+		}
+		s.t.Errorf("unexpected command %q", line)
+		return "", &exec.Error{Name: c.Name, Err: exec.ErrNotFound}
+	}
+	return out, nil
+}
+
+func (s *fakeSystem) LookPath(file string) (string, error) {
+	if p, ok := s.path[file]; ok {
+		return p, nil
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (s *fakeSystem) GOOS() string             { return s.goos }
+func (s *fakeSystem) Getenv(key string) string { return s.env[key] }
+func (s *fakeSystem) Getppid() int             { return s.ppid }
+
+func (s *fakeSystem) Environ() []string {
+	var env []string
+	for key, value := range s.env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+func (s *fakeSystem) Getwd() (string, error) {
+	if s.cwd == "" {
+		return "", fs.ErrNotExist
+	}
+	return s.cwd, nil
+}
+
+func (s *fakeSystem) UserHomeDir() (string, error) {
+	if s.home == "" {
+		return "", fs.ErrNotExist
+	}
+	return s.home, nil
+}
+
+// fsys serves files from a MapFS, which takes paths without the leading /
+func (s *fakeSystem) fsys() fstest.MapFS {
+	m := fstest.MapFS{}
+	for name, data := range s.files {
+		m[strings.TrimPrefix(name, "/")] = &fstest.MapFile{Data: []byte(data), Mode: 0644}
+	}
+	return m
+}
+
+// relative turns an absolute path into a MapFS name, also when the
+// collectors built it with Windows separators
+func relative(name string) string {
+	if name = strings.Trim(strings.ReplaceAll(name, `\`, "/"), "/"); name == "" {
+		return "."
+	}
+	return name
+}
+
+func (s *fakeSystem) Open(name string) (fs.File, error) { return s.fsys().Open(relative(name)) }
+func (s *fakeSystem) ReadFile(name string) ([]byte, error) {
+	return s.fsys().ReadFile(relative(name))
+}
+func (s *fakeSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return s.fsys().ReadDir(relative(name))
+}
+func (s *fakeSystem) Stat(name string) (fs.FileInfo, error) { return s.fsys().Stat(relative(name)) }
+
+func (s *fakeSystem) Readlink(name string) (string, error) {
+	if target, ok := s.links[name]; ok {
+		return target, nil
+	}
+	return "", fs.ErrNotExist
+}
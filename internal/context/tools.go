@@ -0,0 +1,321 @@
+package context
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTools are looked for on PATH unless the config replaces the list
+var DefaultTools = []string{
+	// Languages and package managers
+	"git", "go", "node", "npm", "pnpm", "yarn", "bun", "deno",
+	"python3", "python", "pip", "uv", "poetry", "ruby", "gem",
+	"rustc", "cargo", "java", "mvn", "gradle", "dotnet", "php", "composer",
+	// Build tools
+	"make", "just", "task", "cmake",
+	// Containers and infrastructure
+	"docker", "podman", "kubectl", "helm", "kind", "minikube", "k9s",
+	"terraform", "tofu", "ansible", "aws", "gcloud", "az",
+	// Text and file utilities
+	"jq", "yq", "rg", "fd", "fzf", "bat", "eza", "sed", "awk", "gawk",
+	"curl", "wget", "rsync", "tar", "zip", "unzip",
+	// Media and misc
+	"ffmpeg", "magick", "convert", "ssh", "gh", "sqlite3", "psql", "mysql", "redis-cli",
+}
+
+// versionArgs holds the version flag of tools that don't accept --version
+var versionArgs = map[string][]string{
+	"go":        {"version"},
+	"java":      {"-version"},
+	"kubectl":   {"version", "--client"},
+	"helm":      {"version", "--short"},
+	"terraform": {"version"},
+	"tofu":      {"version"},
+	"dotnet":    {"--version"},
+	"ssh":       {"-V"},
+}
+
+// noVersionProbe lists tools whose version command is slow or noisy; they
+// are reported without a version
+var noVersionProbe = map[string]bool{
+	"gcloud": true,
+	"az":     true,
+	"awk":    true,
+	"sed":    true,
+}
+
+const (
+	// versionTimeout bounds a single `tool --version` probe
+	versionTimeout = 1500 * time.Millisecond
+	// versionWorkers caps concurrent probes
+	versionWorkers = 8
+	// probeMargin is kept free before the collector's deadline so that
+	// the probes finished so far can still be cached
+	probeMargin = 500 * time.Millisecond
+	// toolsCacheTTL expires the cache even if PATH looks unchanged
+	toolsCacheTTL = 24 * time.Hour
+)
+
+var versionRe = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// Tool is a tool found on PATH
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// ToolsInfo lists development tools found on PATH
+type ToolsInfo struct {
+	Tools []Tool
+	// Cached is true when the result came from the cache
+	Cached bool
+}
+
+func (d ToolsInfo) Facts() []Fact {
+	var names []string
+	for _, t := range d.Tools {
+		if t.Version != "" {
+			names = append(names, t.Name+" "+t.Version)
+		} else {
+			names = append(names, t.Name)
+		}
+	}
+	return []Fact{{"Available Tools", strings.Join(names, ", ")}}
+}
+
+// Has reports whether the named tool was found
+func (d ToolsInfo) Has(name string) bool {
+	for _, t := range d.Tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	Register(Collector{Name: "tools", Timeout: 5 * time.Second, Collect: func(ctx gocontext.Context, opts Options) (Data, error) {
+		tools := opts.Tools
+		if len(tools) == 0 {
+			tools = DefaultTools
+		}
+		tools = append(append([]string(nil), tools...), opts.ExtraTools...)
+		return discoverTools(ctx, tools, opts.CacheDir), nil
+	}})
+}
+
+// toolsCache is the on-disk form of a tools discovery
+type toolsCache struct {
+	// Key covers PATH and the requested tool list
+	Key string `json:"key"`
+	// DirTimes records the mtime of each PATH directory, which changes
+	// when a binary is installed or removed
+	DirTimes map[string]int64 `json:"dir_times"`
+	Created  time.Time        `json:"created"`
+	Tools    []Tool           `json:"tools"`
+	// Pending names the tools whose version probe was cut short; the next
+	// run probes only those
+	Pending []string `json:"pending,omitempty"`
+}
+
+// discoverTools finds tools on PATH and probes their versions in parallel,
+// reusing a cached result while PATH is unchanged. Probes still running
+// near ctx's deadline are abandoned and retried on the next run, so a slow
+// first run still leaves a cache behind.
+func discoverTools(ctx gocontext.Context, tools []string, cacheDir string) ToolsInfo {
+	pathDirs := filepath.SplitList(sys.Getenv("PATH"))
+	key := toolsCacheKey(pathDirs, tools)
+	cacheFile := ""
+	cache := toolsCache{Key: key, Created: time.Now()}
+	cached := false
+	if cacheDir != "" {
+		cacheFile = filepath.Join(cacheDir, "tools.json")
+		if c, ok := loadToolsCache(cacheFile, key, pathDirs); ok {
+			if len(c.Pending) == 0 {
+				return ToolsInfo{Tools: c.Tools, Cached: true}
+			}
+			cache, cached = c, true
+		}
+	}
+
+	if !cached {
+		seen := map[string]bool{}
+		for _, name := range tools {
+			// A path would make LookPath accept a program outside PATH,
+			// such as a script in the current repository
+			if seen[name] || strings.ContainsAny(name, `/\`) {
+				continue
+			}
+			seen[name] = true
+			if _, err := sys.LookPath(name); err == nil {
+				cache.Tools = append(cache.Tools, Tool{Name: name})
+				if !noVersionProbe[name] {
+					cache.Pending = append(cache.Pending, name)
+				}
+			}
+		}
+	}
+
+	probeCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel gocontext.CancelFunc
+		probeCtx, cancel = gocontext.WithDeadline(ctx, deadline.Add(-probeMargin))
+		defer cancel()
+	}
+
+	pending := map[string]bool{}
+	for _, name := range cache.Pending {
+		pending[name] = true
+	}
+
+	// Probe versions with a bounded number of workers
+	done := map[string]bool{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, versionWorkers)
+	for i := range cache.Tools {
+		if !pending[cache.Tools[i].Name] {
+			continue
+		}
+		wg.Add(1)
+		go func(t *Tool) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if probeCtx.Err() != nil {
+				return
+			}
+			version := probeVersion(probeCtx, t.Name)
+			// A probe that ran out of budget, rather than its own
+			// timeout, is tried again next time
+			if probeCtx.Err() != nil && version == "" {
+				return
+			}
+			t.Version = version
+			mu.Lock()
+			done[t.Name] = true
+			mu.Unlock()
+		}(&cache.Tools[i])
+	}
+	wg.Wait()
+
+	cache.Pending = nil
+	for _, t := range cache.Tools {
+		if pending[t.Name] && !done[t.Name] {
+			cache.Pending = append(cache.Pending, t.Name)
+		}
+	}
+
+	if cacheFile != "" {
+		cache.DirTimes = dirTimes(pathDirs)
+		saveToolsCache(cacheFile, cache)
+	}
+
+	return ToolsInfo{Tools: cache.Tools}
+}
+
+// probeVersion runs the tool's version command and extracts a version
+func probeVersion(ctx gocontext.Context, name string) string {
+	args, ok := versionArgs[name]
+	if !ok {
+		args = []string{"--version"}
+	}
+	return runVersion(ctx, name, args)
+}
+
+// runVersion runs a version command and extracts the first version number
+func runVersion(ctx gocontext.Context, bin string, args []string) string {
+	ctx, cancel := gocontext.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+
+	// Some tools (java, ssh) print their version on stderr
+	out, _ := sys.Run(ctx, command{Name: bin, Args: args, Stderr: true})
+	return versionRe.FindString(out)
+}
+
+// toolsCacheKey identifies a PATH and tool list combination
+func toolsCacheKey(pathDirs, tools []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q", pathDirs, tools)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dirTimes returns the mtime of each existing PATH directory
+func dirTimes(dirs []string) map[string]int64 {
+	times := map[string]int64{}
+	for _, dir := range dirs {
+		if info, err := sys.Stat(dir); err == nil {
+			times[dir] = info.ModTime().UnixNano()
+		}
+	}
+	return times
+}
+
+// loadToolsCache returns the cache if it matches key and no PATH directory
+// has changed since it was written
+func loadToolsCache(path, key string, pathDirs []string) (toolsCache, bool) {
+	var cache toolsCache
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache, false
+	}
+
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return cache, false
+	}
+
+	if cache.Key != key || time.Since(cache.Created) > toolsCacheTTL {
+		return cache, false
+	}
+
+	current := dirTimes(pathDirs)
+	if len(current) != len(cache.DirTimes) {
+		return cache, false
+	}
+	for dir, mtime := range current {
+		if cache.DirTimes[dir] != mtime {
+			return cache, false
+		}
+	}
+
+	return cache, true
+}
+
+// saveToolsCache writes the cache, ignoring errors: it is only an
+// optimisation
+func saveToolsCache(path string, cache toolsCache) {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	// Write to a unique file first so concurrent runs never see a
+	// partial cache
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tools-*.json")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
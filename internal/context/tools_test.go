@@ -0,0 +1,47 @@
+package context
+
+import (
+	gocontext "context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDiscoverToolsCachesPartialProbe checks that a first run cut short by
+// its deadline still caches the versions it found, and that the next run
+// only probes the tools left over
+func TestDiscoverToolsCachesPartialProbe(t *testing.T) {
+	s := newFakeSystem("linux")
+	s.env["PATH"] = "/usr/bin"
+	s.path["git"] = "/usr/bin/git"
+	s.path["docker"] = "/usr/bin/docker"
+	s.commands["git --version"] = "git version 2.43.0"
+	s.hangs = map[string]bool{"docker --version": true}
+	useSystem(t, s)
+	cacheDir := t.TempDir()
+	tools := []string{"git", "docker"}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), probeMargin+200*time.Millisecond)
+	defer cancel()
+	got := discoverTools(ctx, tools, cacheDir)
+	if want := []Tool{{Name: "git", Version: "2.43.0"}, {Name: "docker"}}; !reflect.DeepEqual(got.Tools, want) {
+		t.Fatalf("first run found %v, want %v", got.Tools, want)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("probes ran past the collector deadline")
+	}
+
+	// git must not be probed again: the fake fails the test if it is
+	delete(s.commands, "git --version")
+	delete(s.hangs, "docker --version")
+	s.commands["docker --version"] = "Docker version 27.3.1, build ce12230"
+	got = discoverTools(gocontext.Background(), tools, cacheDir)
+	if want := []Tool{{Name: "git", Version: "2.43.0"}, {Name: "docker", Version: "27.3.1"}}; !reflect.DeepEqual(got.Tools, want) {
+		t.Fatalf("second run found %v, want %v", got.Tools, want)
+	}
+
+	got = discoverTools(gocontext.Background(), tools, cacheDir)
+	if !got.Cached {
+		t.Error("complete result was not served from the cache")
+	}
+}
@@ -0,0 +1,208 @@
+// Package integration provides the shell snippets installed with
+// `how init <shell>`. They wrap the how binary in a shell function that
+// passes state only the running shell knows, such as its aliases, through
+// environment variables.
+package integration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Environment variables set by the shell integration
+const (
+	EnvShell        = "HOW_SHELL"
+	EnvShellVersion = "HOW_SHELL_VERSION"
+	EnvAliases      = "HOW_SHELL_ALIASES"
+	EnvFunctions    = "HOW_SHELL_FUNCTIONS"
+	// EnvLastCommand and EnvLastStatus describe the command run before how
+	EnvLastCommand = "HOW_LAST_COMMAND"
+	EnvLastStatus  = "HOW_LAST_STATUS"
+	// EnvHistory holds the recent in-memory history as numbered lines,
+	// and EnvHistFile the shell's history file
+	EnvHistory  = "HOW_SHELL_HISTORY"
+	EnvHistFile = "HOW_HISTFILE"
+	// EnvLastStderr is the path of a file holding the tail of that
+	// command's stderr, set only when stderr capture is on
+	EnvLastStderr = "HOW_LAST_STDERR"
+)
+
+// Env lists every variable the integration sets for how. They describe the
+// shell how was started from, so commands how runs should not inherit them.
+var Env = []string{
+	EnvShell, EnvShellVersion, EnvAliases, EnvFunctions,
+	EnvLastCommand, EnvLastStatus, EnvHistory, EnvHistFile, EnvLastStderr,
+}
+
+const bashScript = `# how shell integration for bash
+# Add to ~/.bashrc: eval "$(how init bash)"
+# Set HOW_CAPTURE_STDERR=1 before it to also capture the stderr of commands.
+# The DEBUG trap runs before each simple command; the first one after the
+# prompt is recorded while still pending, as how itself is one too
+__how_preexec() {
+  if [[ -n $__how_at_prompt && $BASH_COMMAND != __how_precmd ]]; then
+    __how_at_prompt=
+    __how_pending_cmd=$BASH_COMMAND
+  fi
+}
+__how_precmd() {
+  local status=$?
+  __how_last_status=$status
+  __how_at_prompt=
+  # The trap only sees the first simple command of the line, so take the
+  # whole line from the history when the command added an entry to it. It
+  # did not if HISTCONTROL or HISTIGNORE kept it out.
+  local entry
+  entry=$(HISTTIMEFORMAT= builtin history 1)
+  if [[ -n $__how_pending_cmd ]]; then
+    __how_last_cmd=$__how_pending_cmd
+    if [[ $entry != "$__how_prompt_hist" && $entry =~ ^\ *[0-9]+\*?\ +(.*)$ ]]; then
+      __how_last_cmd=${BASH_REMATCH[1]}
+    fi
+    __how_pending_cmd=
+  fi
+  __how_prompt_hist=$entry
+  # bash writes its prompt and line editing to stderr as well, so only
+  # keep what follows the mark PS0 printed once the command was read
+  if [[ -n $__how_stderr_dir && -s $__how_stderr_dir/stderr ]]; then
+    local err
+    err=$(tail -c 65536 "$__how_stderr_dir/stderr")
+    if [[ $err == *"$__how_mark"* ]]; then
+      printf '%s' "${err##*"$__how_mark"}" | tail -c 4096 > "$__how_stderr_dir/last"
+    fi
+    : > "$__how_stderr_dir/stderr"
+  fi
+  return $status
+}
+PROMPT_COMMAND="__how_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};__how_at_prompt=1"
+# Keep a DEBUG trap that was already set
+__how_trap_args() { __how_prev_debug=$3; }
+eval "__how_trap_args $(trap -p DEBUG)"
+trap "__how_preexec${__how_prev_debug:+;$__how_prev_debug}" DEBUG
+
+# PS0, printed to stderr after a command is read, needs bash 4.4. The mark
+# is the semantic prompt "command output starts" sequence, which terminals
+# either use or ignore.
+__how_mark=$'\e]133;C\a'
+if [[ -n $HOW_CAPTURE_STDERR && -z $__how_stderr_dir ]] &&
+  (( BASH_VERSINFO[0] > 4 || BASH_VERSINFO[0] == 4 && BASH_VERSINFO[1] >= 4 )); then
+  # A private directory, so that no other user can swap the files for links
+  if [[ -n $XDG_RUNTIME_DIR ]]; then
+    __how_stderr_dir=$(mktemp -d "$XDG_RUNTIME_DIR/how.XXXXXX")
+  else
+    __how_stderr_dir=$(mktemp -d "${TMPDIR:-/tmp}/how.XXXXXX")
+  fi
+  if [[ -n $__how_stderr_dir ]]; then
+    PS0=$__how_mark$PS0
+    exec 2> >(tee -a "$__how_stderr_dir/stderr" >&2)
+  fi
+fi
+
+how() {
+  HOW_SHELL=bash \
+  HOW_SHELL_VERSION="$BASH_VERSION" \
+  HOW_SHELL_ALIASES="$(alias)" \
+  HOW_SHELL_FUNCTIONS="$(compgen -A function)" \
+  HOW_SHELL_HISTORY="$(HISTTIMEFORMAT= builtin history 50)" \
+  HOW_HISTFILE="$HISTFILE" \
+  HOW_LAST_COMMAND="$__how_last_cmd" \
+  HOW_LAST_STATUS="$__how_last_status" \
+  HOW_LAST_STDERR="${__how_stderr_dir:+$__how_stderr_dir/last}" \
+  command how "$@"
+}
+`
+
+const zshScript = `# how shell integration for zsh
+# Add to ~/.zshrc: eval "$(how init zsh)"
+# Set HOW_CAPTURE_STDERR=1 before it to also capture the stderr of commands.
+# preexec also runs for how itself, so the command is only recorded once
+# precmd knows it finished
+__how_preexec() {
+  __how_pending_cmd=$1
+  if [[ -n $__how_stderr_dir ]]; then
+    : > "$__how_stderr_dir/stderr"
+    __how_running=1
+  fi
+}
+__how_precmd() {
+  __how_last_status=$?
+  __how_last_cmd=$__how_pending_cmd
+  # Only keep what was written while a command ran
+  if [[ -n $__how_running ]]; then
+    tail -c 4096 "$__how_stderr_dir/stderr" > "$__how_stderr_dir/last"
+    __how_running=
+  fi
+}
+preexec_functions+=(__how_preexec)
+# First, so that $? is still the status of the command
+precmd_functions=(__how_precmd $precmd_functions)
+
+if [[ -n $HOW_CAPTURE_STDERR && -z $__how_stderr_dir ]]; then
+  # A private directory, so that no other user can swap the files for links
+  if [[ -n $XDG_RUNTIME_DIR ]]; then
+    __how_stderr_dir=$(mktemp -d "$XDG_RUNTIME_DIR/how.XXXXXX")
+  else
+    __how_stderr_dir=$(mktemp -d "${TMPDIR:-/tmp}/how.XXXXXX")
+  fi
+  if [[ -n $__how_stderr_dir ]]; then
+    exec 2> >(tee -a "$__how_stderr_dir/stderr" >&2)
+  fi
+fi
+
+how() {
+  HOW_SHELL=zsh \
+  HOW_SHELL_VERSION="$ZSH_VERSION" \
+  HOW_SHELL_ALIASES="$(alias)" \
+  HOW_SHELL_FUNCTIONS="$(print -rl -- ${(k)functions})" \
+  HOW_SHELL_HISTORY="$(fc -l -50 2>/dev/null)" \
+  HOW_HISTFILE="$HISTFILE" \
+  HOW_LAST_COMMAND="$__how_last_cmd" \
+  HOW_LAST_STATUS="$__how_last_status" \
+  HOW_LAST_STDERR="${__how_stderr_dir:+$__how_stderr_dir/last}" \
+  command how "$@"
+}
+`
+
+const fishScript = `# how shell integration for fish
+# Add to ~/.config/fish/config.fish: how init fish | source
+function __how_postexec --on-event fish_postexec
+  set -g __how_last_status $status
+  set -g __how_last_cmd $argv[1]
+end
+
+function how
+  set -lx HOW_SHELL fish
+  set -lx HOW_SHELL_VERSION $FISH_VERSION
+  set -lx HOW_SHELL_ALIASES (alias | string collect)
+  set -lx HOW_SHELL_FUNCTIONS (functions -n | string collect)
+  set -lx HOW_LAST_COMMAND $__how_last_cmd
+  set -lx HOW_LAST_STATUS $__how_last_status
+  command how $argv
+end
+`
+
+var scripts = map[string]string{
+	"bash": bashScript,
+	"zsh":  zshScript,
+	"fish": fishScript,
+}
+
+// Shells returns the shells with an integration script
+func Shells() []string {
+	var names []string
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Script returns the integration snippet for shell
+func Script(shell string) (string, error) {
+	script, ok := scripts[shell]
+	if !ok {
+		return "", fmt.Errorf("no integration for %q (supported: %s)", shell, strings.Join(Shells(), ", "))
+	}
+	return script, nil
+}
@@ -0,0 +1,67 @@
+package platform
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// IsSSH checks if we're running in an SSH session
+func IsSSH() bool {
+	return DetectSSH(os.Getenv)
+}
+
+// DetectSSH is IsSSH reading the environment through getenv
+func DetectSSH(getenv func(string) string) bool {
+	return getenv("SSH_CLIENT") != "" || getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != ""
+}
+
+// IsWSL checks if we're running in Windows Subsystem for Linux
+func IsWSL() bool {
+	return DetectWSL(os.Getenv, os.ReadFile)
+}
+
+// DetectWSL is IsWSL reading the environment and files through getenv and
+// readFile
+func DetectWSL(getenv func(string) string, readFile func(string) ([]byte, error)) bool {
+	// Check for WSL-specific environment variable
+	if getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	// Check /proc/version for Microsoft/WSL indicators
+	if data, err := readFile("/proc/version"); err == nil {
+		version := strings.ToLower(string(data))
+		return strings.Contains(version, "microsoft") || strings.Contains(version, "wsl")
+	}
+	return false
+}
+
+// StdinIsTerminal reports whether stdin is a terminal rather than a pipe or
+// file
+func StdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// StdinIsPiped reports whether stdin is a pipe or a regular file, the two
+// kinds of input that end. Sockets and /dev/null, as under ssh, cron or CI,
+// are not read.
+func StdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && (info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular())
+}
+
+// StdinIsFile reports whether stdin is a regular file, as in how < log.txt
+func StdinIsFile() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode().IsRegular()
+}
+
+// OpenTerminal opens the controlling terminal for reading, so prompts work
+// while stdin is a pipe. The caller closes it.
+func OpenTerminal() (*os.File, error) {
+	if runtime.GOOS == "windows" {
+		return os.Open("CONIN$")
+	}
+	return os.Open("/dev/tty")
+}
@@ -0,0 +1,95 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geoh/how/internal/context"
+)
+
+// Params holds everything that goes into a prompt
+type Params struct {
+	Question string
+	// Context is rendered fact by fact, so new collectors show up without
+	// changes here
+	Context *context.SystemContext
+	// Facts are user or project statements from the config
+	Facts []string
+	// Instructions are extra rules from the config or active profile
+	Instructions string
+	// Input is text piped to how on stdin
+	Input string
+}
+
+// Build renders the prompt sent to the model
+func Build(p Params) string {
+	var contextLines strings.Builder
+	if p.Context != nil {
+		for _, fact := range p.Context.Facts() {
+			fmt.Fprintf(&contextLines, "-   **%s:** %s\n", fact.Label, fact.Value)
+		}
+	}
+
+	var extra strings.Builder
+	if len(p.Facts) > 0 {
+		extra.WriteString("\nPROJECT FACTS:\n")
+		for _, fact := range p.Facts {
+			fmt.Fprintf(&extra, "-   %s\n", fact)
+		}
+	}
+	if p.Instructions != "" {
+		fmt.Fprintf(&extra, "\nADDITIONAL INSTRUCTIONS:\n%s\n", p.Instructions)
+	}
+	if p.Input != "" {
+		fmt.Fprintf(&extra, "\nINPUT (piped to stdin):\n```\n%s\n```\n", p.Input)
+	}
+
+	shell := "Unknown"
+	if p.Context != nil {
+		if info, ok := p.Context.Get("shell").(context.ShellInfo); ok {
+			shell = info.Name
+		}
+	}
+
+	return fmt.Sprintf(`SYSTEM:
+You are an expert, concise shell assistant. Your goal is to provide accurate, executable shell commands.
+
+CONTEXT:
+%s
+RULES:
+1.  **Primary Goal:** Generate *only* the exact, executable shell command(s) for the %s environment.
+2.  **Context is Key:** Use the CONTEXT (CWD, Files, OS), any PROJECT FACTS and any INPUT to write specific, correct commands.
+3.  **No Banter:** Do NOT include greetings, sign-offs, or conversational filler (e.g., "Here is the command:").
+4.  **Safety:** If a command is complex or destructive (e.g., `+"`rm -rf`, `find -delete`"+`), add a single-line comment (`+"`# ...`"+`) *after* the command explaining what it does.
+5.  **Questions:** If the user asks a question (e.g., "what is `+"`ls`"+`?"), provide a concise, one-line answer. Do not output a command.
+6.  **Ambiguity:** If the request is unclear, ask a single, direct clarifying question. Start the line with `+"`#`"+`.
+%s
+REQUEST:
+%s
+
+RESPONSE:
+`, contextLines.String(), shell, extra.String(), p.Question)
+}
+
+// Correction extends a prompt and the model's response to it with the
+// problems found in the response, asking for a corrected command
+func Correction(prompt, response string, problems []string) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString(response)
+	b.WriteString("\n\nPROBLEMS FOUND IN THE RESPONSE:\n")
+	for _, p := range problems {
+		fmt.Fprintf(&b, "-   %s\n", p)
+	}
+	b.WriteString("\nREQUEST:\nGive a corrected response that avoids these problems, following the same RULES.\n\nRESPONSE:\n")
+	return b.String()
+}
+
+// FixQuestion is the request sent by `how fix`; the last command itself is
+// in the context
+func FixQuestion(last context.LastCommandInfo) string {
+	if last.Failed() {
+		return "My last command failed. Explain the cause in a single `#` comment line, then give the corrected command."
+	}
+	return "My last command did not do what I wanted. Give a corrected or improved command."
+}
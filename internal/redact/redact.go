@@ -0,0 +1,101 @@
+// Package redact removes secrets from text before it leaves the machine.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule replaces matches of Pattern with Replacement, which may refer to
+// submatches to keep, e.g. the "password=" before a value
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Redaction counts the matches of one rule
+type Redaction struct {
+	Rule  string
+	Count int
+}
+
+func (r Redaction) String() string {
+	return fmt.Sprintf("%s (%d)", r.Rule, r.Count)
+}
+
+// rule builds a Rule; "${marker}" in replacement marks where the
+// redaction marker goes, and an empty replacement is just the marker
+func rule(name, pattern, replacement string) Rule {
+	marker := "<redacted:" + name + ">"
+	if replacement == "" {
+		replacement = marker
+	}
+	return Rule{
+		Name:        name,
+		Pattern:     regexp.MustCompile(pattern),
+		Replacement: strings.ReplaceAll(replacement, "${marker}", marker),
+	}
+}
+
+// Rules are the built-in rules, most specific first
+var Rules = []Rule{
+	rule("private-key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`, ""),
+	rule("google-api-key", `AIza[0-9A-Za-z_\-]{35}`, ""),
+	rule("openai-key", `\bsk-(?:proj-|ant-)?[A-Za-z0-9_\-]{20,}`, ""),
+	rule("github-token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`, ""),
+	rule("gitlab-token", `\bglpat-[A-Za-z0-9_\-]{20,}`, ""),
+	rule("aws-access-key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`, ""),
+	rule("slack-token", `\bxox[abprs]-[A-Za-z0-9\-]{10,}`, ""),
+	rule("jwt", `\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`, ""),
+	rule("url-credentials", `(\b[a-zA-Z][a-zA-Z0-9+.\-]*://[^\s:/@]+:)[^\s@/]+@`, "${1}${marker}@"),
+	rule("bearer-token", `(?i)(\bbearer\s+)[A-Za-z0-9._~+/\-]{8,}=*`, "${1}${marker}"),
+	// The name must end in the secret word, so that settings about secrets
+	// such as max_tokens or password_policy keep their values
+	rule("secret-assignment", `(?i)(\b[A-Z0-9_.\-]*(?:password|passwd|secret(?:_?key)?|token|api_?key|access_?key|private_?key)(?:_?base)?[0-9]*["']?\s*[=:]\s*)["']?[^\s"'<>]{4,}["']?`, "${1}${marker}"),
+}
+
+// Redactor applies the built-in rules and any configured patterns
+type Redactor struct {
+	rules []Rule
+}
+
+// New returns a Redactor with the built-in rules plus patterns, regular
+// expressions whose matches are replaced entirely. Invalid patterns are
+// reported and skipped.
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]Rule(nil), Rules...)}
+	var errs []error
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redact pattern %q: %v", p, err))
+			continue
+		}
+		r.rules = append(r.rules, Rule{Name: "custom", Pattern: re, Replacement: "<redacted:custom>"})
+	}
+	return r, errors.Join(errs...)
+}
+
+// Redact returns s with secrets replaced and what was replaced
+func (r *Redactor) Redact(s string) (string, []Redaction) {
+	counts := map[string]int{}
+	for _, rule := range r.rules {
+		n := len(rule.Pattern.FindAllStringIndex(s, -1))
+		if n == 0 {
+			continue
+		}
+		counts[rule.Name] += n
+		s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+	}
+
+	var redactions []Redaction
+	for name, count := range counts {
+		redactions = append(redactions, Redaction{Rule: name, Count: count})
+	}
+	sort.Slice(redactions, func(i, j int) bool { return redactions[i].Rule < redactions[j].Rule })
+	return s, redactions
+}
@@ -0,0 +1,640 @@
+// Package safety flags generated commands that can destroy data or
+// compromise the system, so they are never run by accident.
+package safety
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Warning is one destructive pattern found in a command
+type Warning struct {
+	// Command is the offending simple command, e.g. "rm -rf /"
+	Command string
+	Reason  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Reason, w.Command)
+}
+
+// Check parses command as a shell script and returns a warning for each
+// destructive pattern in it. Commands the parser rejects, e.g. fish or
+// PowerShell syntax, are checked word by word instead.
+func Check(command string) []Warning {
+	c := &checker{}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		c.checkText(command)
+		return c.warnings
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			c.checkCall(words(n.Args), shellSubst(n))
+		case *syntax.Stmt:
+			call, _ := n.Cmd.(*syntax.CallExpr)
+			for _, r := range n.Redirs {
+				// psql <<EOF ... EOF
+				if r.Hdoc != nil && call != nil && sqlClient(words(call.Args)) >= 0 {
+					c.checkSQL(word(r.Hdoc))
+				}
+				switch r.Op {
+				case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+					if r.Word != nil {
+						c.checkWrite(source(n), word(r.Word))
+					}
+				}
+			}
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				c.checkPipeline(pipeline(n))
+			}
+		case *syntax.FuncDecl:
+			if callsItselfInBackground(n) {
+				c.add(n.Name.Value+"() { ... }", "fork bomb")
+			}
+		}
+		return true
+	})
+	return c.warnings
+}
+
+type checker struct {
+	warnings []Warning
+}
+
+func (c *checker) add(command, reason string) {
+	for _, w := range c.warnings {
+		if w.Command == command && w.Reason == reason {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, Warning{Command: command, Reason: reason})
+}
+
+// wrappers run the command that follows their own options
+var wrappers = map[string]bool{
+	"sudo": true, "doas": true, "nice": true, "nohup": true, "time": true,
+	"command": true, "exec": true, "env": true, "xargs": true, "watch": true,
+	"timeout": true, "ionice": true, "stdbuf": true,
+	// fish
+	"and": true, "or": true, "not": true,
+}
+
+// wrapperValues lists the options of each wrapper that take a value as the
+// next argument, so sudo -u root rm is read as rm rather than root
+var wrapperValues = map[string][]string{
+	"sudo":    {"-u", "--user", "-g", "--group", "-C", "--close-from", "-D", "--chdir", "-h", "--host", "-p", "--prompt", "-r", "--role", "-t", "--type", "-U", "--other-user", "-T", "--command-timeout", "-R", "--chroot"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "--unset", "-C", "--chdir", "-S", "--split-string"},
+	"nice":    {"-n", "--adjustment"},
+	"timeout": {"-s", "--signal", "-k", "--kill-after"},
+	"ionice":  {"-c", "--class", "-n", "--classdata", "-p", "--pid", "-P", "--pgid", "-u", "--uid"},
+	"stdbuf":  {"-i", "--input", "-o", "--output", "-e", "--error"},
+	"xargs":   {"-a", "--arg-file", "-d", "--delimiter", "-E", "-I", "-L", "--max-lines", "-n", "--max-args", "-P", "--max-procs", "-s", "--max-chars"},
+	"watch":   {"-n", "--interval"},
+	"time":    {"-f", "--format", "-o", "--output"},
+	"exec":    {"-a"},
+}
+
+// unwrap strips sudo and similar wrappers, with their options, option
+// values and env assignments, from a command's words
+func unwrap(args []string) []string {
+	for len(args) > 0 && wrappers[path.Base(args[0])] {
+		wrapper := path.Base(args[0])
+		args = args[1:]
+		for len(args) > 0 && (strings.HasPrefix(args[0], "-") || strings.Contains(args[0], "=")) {
+			opt := args[0]
+			args = args[1:]
+			if opt == "--" {
+				break
+			}
+			if takesValue(wrapperValues[wrapper], opt) && len(args) > 0 {
+				args = args[1:]
+			}
+		}
+		// timeout's duration comes before the command
+		if wrapper == "timeout" && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// takesValue reports whether opt, one of a command's arguments, is an
+// option from valued whose value is the next argument. Short options may be
+// bundled, as in -Eu root; a long option given as --user=root carries its
+// own value.
+func takesValue(valued []string, opt string) bool {
+	if strings.HasPrefix(opt, "--") {
+		return !strings.Contains(opt, "=") && contains(valued, opt)
+	}
+	if !strings.HasPrefix(opt, "-") {
+		return false
+	}
+	for i, r := range opt[1:] {
+		if contains(valued, "-"+string(r)) {
+			// The rest of the word, if any, is the value
+			return i == len(opt)-2
+		}
+	}
+	return false
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// gitValues are git's global options that take the next argument as their
+// value, e.g. git -C repo push
+var gitValues = []string{"-C", "-c", "--git-dir", "--work-tree", "--namespace", "--exec-path", "--config-env", "--super-prefix"}
+
+// gitSubcommand returns the index of git's subcommand in args, which start
+// after "git", skipping global options; -1 if there is none
+func gitSubcommand(args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return i
+		}
+		if contains(gitValues, arg) {
+			i++
+		}
+	}
+	return -1
+}
+
+// checkCall checks one simple command. viaShell is set when the command is
+// a shell given a downloaded script, as in sh -c "$(curl ...)".
+func (c *checker) checkCall(args []string, viaShell bool) {
+	full := strings.Join(args, " ")
+	args = unwrap(args)
+	if len(args) == 0 {
+		return
+	}
+	name := path.Base(args[0])
+	flags, operands := splitFlags(args[1:])
+
+	switch {
+	case name == "rm":
+		recursive := flags["r"] || flags["R"] || flags["recursive"]
+		for _, op := range operands {
+			switch {
+			case recursive && broadPath(op):
+				c.add(full, "recursive delete of "+op)
+			case systemPath(op):
+				c.add(full, "deletes system path "+op)
+			}
+		}
+		if flags["no-preserve-root"] {
+			c.add(full, "recursive delete with --no-preserve-root")
+		}
+
+	case name == "dd":
+		for _, arg := range args[1:] {
+			if target, ok := strings.CutPrefix(arg, "of="); ok && strings.HasPrefix(target, "/dev/") && !harmlessDevice(target) {
+				c.add(full, "raw write to device "+target)
+			}
+		}
+
+	case name == "mkfs" || strings.HasPrefix(name, "mkfs.") || name == "mke2fs" || name == "mkswap" || name == "wipefs":
+		c.add(full, "formats a filesystem")
+
+	case name == "chmod" || name == "chown":
+		if !flags["R"] && !flags["recursive"] || len(operands) == 0 {
+			break
+		}
+		if mode := operands[0]; name == "chmod" && (mode == "777" || mode == "0777" || mode == "a+rwx" || mode == "ugo+rwx") {
+			c.add(full, "recursively makes files writable by everyone")
+			break
+		}
+		for _, op := range operands[1:] {
+			if broadPath(op) || systemPath(op) {
+				c.add(full, "recursive "+name+" of "+op)
+			}
+		}
+
+	case name == "Remove-Item" || name == "ri" || name == "del" || name == "erase" || name == "rd" || name == "rmdir":
+		// PowerShell and cmd spell recursion -Recurse and /s
+		recursive := false
+		for _, arg := range args[1:] {
+			switch strings.ToLower(arg) {
+			case "-recurse", "-r", "/s":
+				recursive = true
+			}
+		}
+		for _, op := range operands {
+			// cmd switches like /s and /q are not paths
+			if len(op) == 2 && op[0] == '/' {
+				continue
+			}
+			switch {
+			case recursive && broadPath(op):
+				c.add(full, "recursive delete of "+op)
+			case systemPath(op):
+				c.add(full, "deletes system path "+op)
+			}
+		}
+
+	case name == "Format-Volume" || name == "Clear-Disk" || name == "format" && len(operands) > 0 && strings.HasSuffix(operands[0], ":"):
+		c.add(full, "formats a filesystem")
+
+	case name == "git":
+		sub := gitSubcommand(args[1:])
+		if sub < 0 || args[1+sub] != "push" {
+			break
+		}
+		flags, operands := splitFlags(args[2+sub:])
+		force := flags["f"] || flags["force"] || flags["force-with-lease"] || flags["mirror"]
+		remove := flags["d"] || flags["delete"]
+		// A refspec starting with + forces that ref, one starting with :
+		// pushes nothing to it, deleting it
+		for _, op := range operands {
+			force = force || strings.HasPrefix(op, "+")
+			remove = remove || strings.HasPrefix(op, ":")
+		}
+		if force {
+			c.add(full, "force push rewrites remote history")
+		}
+		if remove {
+			c.add(full, "push deletes a remote branch or tag")
+		}
+
+	case name == "find":
+		for _, arg := range args[1:] {
+			if arg == "-delete" {
+				c.add(full, "find -delete removes every match below the start directory")
+			}
+		}
+		for i, arg := range args[1:] {
+			if (arg == "-exec" || arg == "-execdir") && i+2 < len(args) && path.Base(args[i+2]) == "rm" {
+				c.add(full, "find -exec rm removes every match below the start directory")
+			}
+		}
+
+	case name == "tee" || name == "cp" || name == "mv" || name == "install" || name == "ln" || name == "truncate":
+		targets := operands
+		if name != "tee" && name != "truncate" && len(operands) > 1 {
+			targets = operands[len(operands)-1:]
+		}
+		for _, target := range targets {
+			c.checkWrite(full, target)
+		}
+
+	case shells[name]:
+		if viaShell {
+			c.add(full, "runs a script downloaded from the internet")
+		}
+		// Check the script given to sh -c like the command itself
+		if script, ok := shellScript(name, args[1:]); ok {
+			for _, w := range Check(script) {
+				c.add(w.Command, w.Reason)
+			}
+		}
+	}
+
+	if i := sqlClient(args); i >= 0 {
+		c.checkSQL(strings.Join(args[i+1:], " "))
+	}
+}
+
+// scriptShells are the shells whose -c option takes a script in the same
+// language, as opposed to interpreters like python -c
+var scriptShells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true}
+
+// shellScript returns the script passed to a shell with -c, which may be
+// bundled with other short options as in bash -ec
+func shellScript(name string, args []string) (string, bool) {
+	if !scriptShells[name] {
+		return "", false
+	}
+	for i, arg := range args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return "", false
+		}
+		if !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// sqlClients run the SQL they are given with -c, -e or on stdin
+var sqlClients = map[string]bool{
+	"psql": true, "mysql": true, "mariadb": true, "sqlite3": true,
+	"duckdb": true, "sqlcmd": true, "clickhouse-client": true,
+}
+
+// sqlClient returns the index of a SQL client in args, which may come
+// after a wrapper such as docker exec db, or -1 if there is none
+func sqlClient(args []string) int {
+	for i, arg := range args {
+		if sqlClients[path.Base(arg)] {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkWrite flags writes to system directories and block devices
+func (c *checker) checkWrite(command, target string) {
+	switch {
+	case strings.HasPrefix(target, "/dev/"):
+		if !harmlessDevice(target) {
+			c.add(command, "writes to device "+target)
+		}
+	case systemPath(target):
+		c.add(command, "writes to system path "+target)
+	}
+}
+
+// downloaders fetch URLs; shells run what they read on stdin
+var (
+	downloaders = map[string]bool{"curl": true, "wget": true, "fetch": true, "iwr": true, "Invoke-WebRequest": true, "irm": true, "Invoke-RestMethod": true}
+	shells      = map[string]bool{
+		"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+		"python": true, "python3": true, "perl": true, "ruby": true, "node": true,
+		"iex": true, "Invoke-Expression": true, "pwsh": true, "powershell": true,
+	}
+)
+
+// checkPipeline flags a download piped into an interpreter and SQL piped
+// into a SQL client
+func (c *checker) checkPipeline(cmds [][]string) {
+	for i := 1; i < len(cmds); i++ {
+		if sqlClient(cmds[i]) >= 0 {
+			for _, args := range cmds[:i] {
+				c.checkSQL(strings.Join(args, " "))
+			}
+		}
+	}
+
+	downloaded := false
+	for _, args := range cmds {
+		args = unwrap(args)
+		if len(args) == 0 {
+			continue
+		}
+		name := path.Base(args[0])
+		if downloaders[name] {
+			downloaded = true
+		}
+		if downloaded && shells[name] {
+			var parts []string
+			for _, a := range cmds {
+				parts = append(parts, strings.Join(a, " "))
+			}
+			c.add(strings.Join(parts, " | "), "runs a script downloaded from the internet")
+			return
+		}
+	}
+}
+
+var sqlRe = regexp.MustCompile(`(?i)\b(drop\s+(table|database|schema)|truncate\s+table)\b`)
+
+// checkSQL flags destructive SQL in text given to a SQL client, e.g. its
+// -c argument or a heredoc
+func (c *checker) checkSQL(command string) {
+	for _, line := range strings.Split(command, "\n") {
+		if m := sqlRe.FindString(line); m != "" {
+			c.add(strings.TrimSpace(line), "destructive SQL ("+strings.ToUpper(strings.Join(strings.Fields(m), " "))+")")
+		}
+	}
+}
+
+var (
+	textForkBombRe = regexp.MustCompile(`(\S+)\s*\(\)\s*\{\s*(\S+)\s*\|\s*(\S+)\s*&\s*;?\s*\}`)
+	textRedirectRe = regexp.MustCompile(`>>?\s*(\S+)`)
+	textListRe     = regexp.MustCompile(`\n|;|&&|\|\|`)
+)
+
+// checkText is the fallback for commands that do not parse as bash: it
+// splits pipelines and command lists on their operators and checks the
+// words of each command
+func (c *checker) checkText(command string) {
+	if m := textForkBombRe.FindStringSubmatch(command); m != nil && m[1] == m[2] {
+		c.add(m[0], "fork bomb")
+	}
+	for _, m := range textRedirectRe.FindAllStringSubmatch(command, -1) {
+		c.checkWrite(m[0], strings.Trim(m[1], `'"`))
+	}
+
+	for _, line := range textListRe.Split(command, -1) {
+		var cmds [][]string
+		for _, part := range strings.Split(line, "|") {
+			var args []string
+			for _, f := range strings.Fields(part) {
+				if strings.HasPrefix(f, "#") {
+					break
+				}
+				args = append(args, strings.Trim(f, `'"`))
+			}
+			if len(args) > 0 {
+				c.checkCall(args, false)
+				cmds = append(cmds, args)
+			}
+		}
+		if len(cmds) > 1 {
+			c.checkPipeline(cmds)
+		}
+	}
+}
+
+// splitFlags separates short (bundled) and long flags from operands;
+// everything after "--" is an operand
+func splitFlags(args []string) (map[string]bool, []string) {
+	flags := map[string]bool{}
+	var operands []string
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return flags, append(operands, args[i+1:]...)
+		case strings.HasPrefix(arg, "--"):
+			name, _, _ := strings.Cut(arg[2:], "=")
+			flags[name] = true
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, r := range arg[1:] {
+				flags[string(r)] = true
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return flags, operands
+}
+
+// broadPath reports whether p names the root, a top-level directory, the
+// home directory or everything in the working directory. Forms like ~/.
+// and /./ are cleaned first.
+func broadPath(p string) bool {
+	// C:\ or C:\Windows
+	if len(p) >= 2 && p[1] == ':' && strings.Count(strings.TrimSuffix(strings.ReplaceAll(p, `\`, "/"), "/"), "/") <= 1 {
+		return true
+	}
+
+	// Stand the home directory in for a top-level one; its parents are
+	// just as broad
+	for _, home := range []string{"~", "$HOME", "${HOME}"} {
+		if rest, ok := strings.CutPrefix(p, home); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+			p = "/home" + rest
+			break
+		}
+	}
+	p = path.Clean(strings.TrimSuffix(p, "*"))
+	switch p {
+	case ".", "..":
+		return true
+	}
+	return p == "/" || strings.HasPrefix(p, "/") && strings.Count(p, "/") == 1
+}
+
+// systemDirs hold the operating system; writing there can break it
+var systemDirs = []string{"/etc", "/boot", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/usr", "/sys", "/proc", "/System", "/Library"}
+
+// userDirs are system subdirectories meant for local changes
+var userDirs = []string{"/usr/local", "/usr/src"}
+
+func systemPath(p string) bool {
+	if strings.HasPrefix(strings.ToLower(strings.ReplaceAll(p, `\`, "/")), "c:/windows") {
+		return true
+	}
+	p = path.Clean(p)
+	for _, dir := range userDirs {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return false
+		}
+	}
+	for _, dir := range systemDirs {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// harmlessDevice reports whether writing to the device cannot destroy data
+func harmlessDevice(dev string) bool {
+	switch dev {
+	case "/dev/null", "/dev/zero", "/dev/stdout", "/dev/stderr", "/dev/tty", "/dev/random", "/dev/urandom":
+		return true
+	}
+	return strings.HasPrefix(dev, "/dev/fd/") || strings.HasPrefix(dev, "/dev/pts/")
+}
+
+// pipeline flattens a pipeline into the words of its commands
+func pipeline(b *syntax.BinaryCmd) [][]string {
+	var cmds [][]string
+	for _, s := range []*syntax.Stmt{b.X, b.Y} {
+		switch cmd := s.Cmd.(type) {
+		case *syntax.BinaryCmd:
+			if cmd.Op == syntax.Pipe || cmd.Op == syntax.PipeAll {
+				cmds = append(cmds, pipeline(cmd)...)
+			}
+		case *syntax.CallExpr:
+			cmds = append(cmds, words(cmd.Args))
+		}
+	}
+	return cmds
+}
+
+// shellSubst reports whether call runs a shell on the output of a
+// download, as in sh -c "$(curl ...)" or bash <(wget ...)
+func shellSubst(call *syntax.CallExpr) bool {
+	// Assignments alone, as in DIR=/tmp/x, have no words at all
+	if len(call.Args) < 2 {
+		return false
+	}
+	found := false
+	for _, w := range call.Args[1:] {
+		syntax.Walk(w, func(node syntax.Node) bool {
+			var stmts []*syntax.Stmt
+			switch n := node.(type) {
+			case *syntax.CmdSubst:
+				stmts = n.Stmts
+			case *syntax.ProcSubst:
+				stmts = n.Stmts
+			}
+			for _, s := range stmts {
+				syntax.Walk(s, func(node syntax.Node) bool {
+					if c, ok := node.(*syntax.CallExpr); ok && len(c.Args) > 0 && downloaders[path.Base(word(c.Args[0]))] {
+						found = true
+					}
+					return !found
+				})
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// callsItselfInBackground recognises the classic :(){ :|:& };: shape: a
+// function that pipes itself into itself in the background
+func callsItselfInBackground(f *syntax.FuncDecl) bool {
+	found := false
+	syntax.Walk(f.Body, func(node syntax.Node) bool {
+		s, ok := node.(*syntax.Stmt)
+		if !ok || !s.Background {
+			return true
+		}
+		syntax.Walk(s, func(node syntax.Node) bool {
+			if c, ok := node.(*syntax.CallExpr); ok && len(c.Args) > 0 && word(c.Args[0]) == f.Name.Value {
+				found = true
+			}
+			return !found
+		})
+		return !found
+	})
+	return found
+}
+
+// source prints node as shell source
+func source(node syntax.Node) string {
+	var b strings.Builder
+	syntax.NewPrinter(syntax.SingleLine(true)).Print(&b, node)
+	return b.String()
+}
+
+// words returns the text of each word
+func words(ws []*syntax.Word) []string {
+	var out []string
+	for _, w := range ws {
+		out = append(out, word(w))
+	}
+	return out
+}
+
+// word returns the text of w with quotes removed and parameter expansions
+// and substitutions left as written
+func word(w *syntax.Word) string {
+	var b strings.Builder
+	writeParts(&b, w.Parts)
+	return b.String()
+}
+
+func writeParts(b *strings.Builder, parts []syntax.WordPart) {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			writeParts(b, p.Parts)
+		default:
+			syntax.NewPrinter().Print(b, p)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package safety
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		command string
+		// reason is part of the one warning expected, "" for none
+		reason string
+	}{
+		// Deletes
+		{"rm -rf /", "recursive delete of /"},
+		{"rm -rf ~", "recursive delete of ~"},
+		{"rm -rf $HOME/", "recursive delete of $HOME/"},
+		{"rm -rf *", "recursive delete of *"},
+		{"rm -fr /home", "recursive delete of /home"},
+		{"rm --recursive --force .", "recursive delete of ."},
+		{"sudo rm -rf /", "recursive delete of /"},
+		{"sudo -u root rm -rf /", "recursive delete of /"},
+		{"sudo -Eu root rm -rf /", "recursive delete of /"},
+		{"timeout -s KILL 5 rm -rf /", "recursive delete of /"},
+		{"rm -rf /usr/lib", "deletes system path /usr/lib"},
+		{"rm /etc/passwd", "deletes system path /etc/passwd"},
+		{"rm --no-preserve-root -r /mnt", "--no-preserve-root"},
+		{"Remove-Item -Recurse -Force C:\\", "recursive delete of C:\\"},
+		{"rd /s /q C:\\Windows", "recursive delete of C:\\Windows"},
+		{"rm -rf ~/.", "recursive delete of ~/."},
+		{"rm -rf ~/", "recursive delete of ~/"},
+		{"rm -rf $HOME/.", "recursive delete of $HOME/."},
+		{"rm -rf /./", "recursive delete of /./"},
+		{"rm -rf /usr/../", "recursive delete of /usr/../"},
+		{"rm -rf ./.", "recursive delete of ./."},
+		{"rm -rf ~/../", "recursive delete of ~/../"},
+
+		// Devices and filesystems
+		{"dd if=ubuntu.iso of=/dev/sda bs=4M", "raw write to device /dev/sda"},
+		{"sudo mkfs.ext4 /dev/sdb1", "formats a filesystem"},
+		{"cat image.img > /dev/nvme0n1", "writes to device /dev/nvme0n1"},
+
+		// System files
+		{"echo 'nameserver 1.1.1.1' | sudo tee /etc/resolv.conf", "writes to system path /etc/resolv.conf"},
+		{"echo x >> /etc/hosts", "writes to system path /etc/hosts"},
+		{"sudo cp myls /usr/bin/ls", "writes to system path /usr/bin/ls"},
+		{"chmod -R 777 /var/www", "writable by everyone"},
+		{"sudo chown -R me /usr", "recursive chown of /usr"},
+
+		// Downloaded scripts
+		{"curl -fsSL https://example.com/install.sh | sudo bash", "runs a script downloaded"},
+		{"wget -qO- https://example.com/x | sh", "runs a script downloaded"},
+		{`sh -c "$(curl -fsSL https://example.com/install.sh)"`, "runs a script downloaded"},
+		{"bash <(curl -s https://example.com/x)", "runs a script downloaded"},
+		{"iwr https://example.com/x.ps1 | iex", "runs a script downloaded"},
+
+		// Scripts given to a shell
+		{"sudo sh -c 'rm -rf /'", "recursive delete of /"},
+		{`bash -c "curl -fsSL https://example.com/x | sh"`, "runs a script downloaded"},
+		{"bash -ec 'echo x > /etc/hosts'", "writes to system path /etc/hosts"},
+		{`sh -c "sh -c 'dd if=x of=/dev/sda'"`, "raw write to device /dev/sda"},
+
+		// History and data
+		{"git push --force", "force push"},
+		{"git push -f origin main", "force push"},
+		{"git -C repo push --force", "force push"},
+		{"git -c core.sshCommand=ssh push origin +main", "force push"},
+		{"git push origin :main", "deletes a remote branch"},
+		{"git push --delete origin v1.0", "deletes a remote branch"},
+		{"git push -d origin feature", "deletes a remote branch"},
+		{`psql -c "DROP TABLE users"`, "destructive SQL (DROP TABLE)"},
+		{`mysql -e "drop database shop"`, "destructive SQL (DROP DATABASE)"},
+		{`docker exec db psql -U app -c "TRUNCATE TABLE orders"`, "destructive SQL (TRUNCATE TABLE)"},
+		{`echo "DROP TABLE users;" | sqlite3 app.db`, "destructive SQL (DROP TABLE)"},
+		{"psql app <<EOF\nDROP SCHEMA public CASCADE;\nEOF", "destructive SQL (DROP SCHEMA)"},
+		{"find . -name '*.log' -delete", "find -delete"},
+		{"find /tmp -type f -exec rm {} +", "find -exec rm"},
+
+		// Fork bomb
+		{":(){ :|:& };:", "fork bomb"},
+
+		// Harmless
+		{"rm -rf node_modules", ""},
+		{"rm -rf ./build dist", ""},
+		{"rm /usr/local/bin/foo", ""},
+		{"rm notes.txt", ""},
+		{"echo hello > /dev/null", ""},
+		{"make 2>/dev/null", ""},
+		{"dd if=/dev/zero of=disk.img bs=1M count=100", ""},
+		{"git push origin main", ""},
+		{"git push origin main:release", ""},
+		{"sh -c 'ls'", ""},
+		{"bash -c 'rm -rf build'", ""},
+		{"python3 -c 'print(1)'", ""},
+		{"echo drop table", ""},
+		{"grep -r 'DROP TABLE' migrations/", ""},
+		{"rm -rf ~/build", ""},
+		{"rm -rf ./build/.", ""},
+		{"git -C repo status", ""},
+		{"curl -fsSL https://example.com/data.json | jq .", ""},
+		{"chmod -R 755 ./public", ""},
+		{"find . -name '*.go'", ""},
+		{"DIR=/tmp/x", ""},
+		{"rm file; DROP=1", ""},
+		{"export PATH=$HOME/bin:$PATH", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			warnings := Check(tt.command)
+			if tt.reason == "" {
+				if len(warnings) > 0 {
+					t.Fatalf("Check(%q) = %v, want no warnings", tt.command, warnings)
+				}
+				return
+			}
+			for _, w := range warnings {
+				if strings.Contains(w.Reason, tt.reason) {
+					return
+				}
+			}
+			t.Fatalf("Check(%q) = %v, want a warning about %q", tt.command, warnings, tt.reason)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -60,11 +61,21 @@ func (s *Spinner) Stop() {
 	s.wg.Wait()
 }
 
-// TypewriterPrint prints text with a typewriter effect
-func TypewriterPrint(text string) {
+// Warning prints msg in red on stderr, without color when stderr is not a
+// terminal or NO_COLOR is set
+func Warning(msg string) {
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == "" {
+		msg = "\033[1;31m" + msg + "\033[0m"
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// TypewriterPrint prints text with a typewriter effect, pausing delay
+// between characters
+func TypewriterPrint(text string, delay time.Duration) {
 	for _, char := range text {
 		fmt.Print(string(char))
-		time.Sleep(10 * time.Millisecond)
+		time.Sleep(delay)
 	}
 	fmt.Println()
 }
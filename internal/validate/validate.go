@@ -0,0 +1,298 @@
+// Package validate checks generated commands before they are shown: that
+// they parse in the user's shell and that the programs they call exist.
+package validate
+
+import (
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// shellCheckTimeout bounds a syntax check run by the shell itself
+const shellCheckTimeout = 2 * time.Second
+
+// Issue is a problem likely to stop a command from working
+type Issue struct {
+	// Syntax is set for parse errors, as opposed to missing programs
+	Syntax  bool
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Message
+}
+
+// Options describes the shell the command is meant for
+type Options struct {
+	// Shell is the shell name, e.g. "bash" or "fish"; ShellPath its binary
+	Shell     string
+	ShellPath string
+	// Known are aliases and functions defined in the user's shell
+	Known []string
+	// LookPath finds programs; nil means exec.LookPath
+	LookPath func(string) (string, error)
+	// RunShell runs a shell binary with args and script on stdin,
+	// returning its stderr; nil runs it with os/exec
+	RunShell func(ctx gocontext.Context, bin string, args []string, script string) (string, error)
+}
+
+// dialects maps shells to the parser variant that understands them
+var dialects = map[string]syntax.LangVariant{
+	"bash": syntax.LangBash,
+	"sh":   syntax.LangPOSIX,
+	"dash": syntax.LangPOSIX,
+	"ash":  syntax.LangPOSIX,
+	"ksh":  syntax.LangMirBSDKorn,
+	"mksh": syntax.LangMirBSDKorn,
+}
+
+// noExecArgs make a shell parse a script from stdin without running it,
+// for dialects the parser does not know
+var noExecArgs = map[string][]string{
+	"zsh":  {"-f", "-n"},
+	"fish": {"--no-execute"},
+}
+
+// Check parses command for opts.Shell and reports syntax errors and
+// programs missing from PATH. Shells it cannot check, such as PowerShell,
+// get no issues, and neither do the one-sentence answers the model gives
+// to questions.
+func Check(ctx gocontext.Context, command string, opts Options) []Issue {
+	if isProse(command) {
+		return nil
+	}
+
+	var issues []Issue
+	if lang, ok := dialects[opts.Shell]; ok {
+		if _, err := syntax.NewParser(syntax.Variant(lang)).Parse(strings.NewReader(command), ""); err != nil {
+			issues = append(issues, Issue{Syntax: true, Message: fmt.Sprintf("does not parse as %s: %v", opts.Shell, err)})
+		}
+	} else if args, ok := noExecArgs[opts.Shell]; ok {
+		if err := shellCheck(ctx, command, opts, args); err != nil {
+			issues = append(issues, Issue{Syntax: true, Message: fmt.Sprintf("does not parse as %s: %v", opts.Shell, err)})
+		}
+	} else {
+		return nil
+	}
+
+	for _, name := range missingPrograms(command, opts, opts.lookPath()) {
+		issues = append(issues, Issue{Message: fmt.Sprintf("%s is not installed or not on PATH", name)})
+	}
+	return issues
+}
+
+// Correct asks fix for a new command when command has issues, passing it
+// the problems found, and keeps the answer only if it has fewer issues.
+// Otherwise command and issues are returned unchanged.
+func Correct(ctx gocontext.Context, command string, issues []Issue, opts Options, fix func(problems []string) (string, error)) (string, []Issue, error) {
+	if len(issues) == 0 {
+		return command, issues, nil
+	}
+
+	var problems []string
+	for _, issue := range issues {
+		problems = append(problems, issue.String())
+	}
+	corrected, err := fix(problems)
+	if err != nil || strings.TrimSpace(corrected) == "" {
+		return command, issues, err
+	}
+
+	if correctedIssues := Check(ctx, corrected, opts); len(correctedIssues) < len(issues) {
+		return corrected, correctedIssues, nil
+	}
+	return command, issues, nil
+}
+
+// lookPath returns opts.LookPath, or exec.LookPath when it is not set
+func (opts Options) lookPath() func(string) (string, error) {
+	if opts.LookPath != nil {
+		return opts.LookPath
+	}
+	return exec.LookPath
+}
+
+// isProse reports whether text reads as a sentence rather than a command
+func isProse(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" || !unicode.IsUpper([]rune(text)[0]) {
+		return false
+	}
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!")
+}
+
+// shellCheck has the shell itself parse command without running it
+func shellCheck(ctx gocontext.Context, command string, opts Options, args []string) error {
+	bin := opts.ShellPath
+	if bin == "" {
+		bin = opts.Shell
+	}
+	if _, err := opts.lookPath()(bin); err != nil {
+		// Nothing to check with
+		return nil
+	}
+
+	run := opts.RunShell
+	if run == nil {
+		run = runShell
+	}
+	ctx, cancel := gocontext.WithTimeout(ctx, shellCheckTimeout)
+	defer cancel()
+	stderr, err := run(ctx, bin, args, command+"\n")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		msg := strings.TrimSpace(stderr)
+		if msg == "" {
+			return err
+		}
+		// Keep the first line; the rest points at the error with carets
+		first, _, _ := strings.Cut(msg, "\n")
+		return fmt.Errorf("%s", first)
+	}
+	return nil
+}
+
+// runShell runs bin with script on stdin and returns its stderr
+func runShell(ctx gocontext.Context, bin string, args []string, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdin = strings.NewReader(script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stderr.String(), err
+}
+
+// missingPrograms returns the commands called by command that are neither
+// on PATH nor builtins, functions or aliases. The script is read as bash,
+// which is close enough to find command names in other shells too. A
+// script that installs packages may well provide what it calls, so nothing
+// is reported for it.
+func missingPrograms(command string, opts Options, lookPath func(string) (string, error)) []string {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, name := range opts.Known {
+		name, _, _ = strings.Cut(name, "=")
+		known[name] = true
+	}
+	var names []string
+	installs := false
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.FuncDecl:
+			known[n.Name.Value] = true
+		case *syntax.CallExpr:
+			args := literals(n.Args)
+			if len(args) == 0 {
+				return true
+			}
+			name := commandName(args)
+			if name != "" {
+				names = append(names, name)
+			}
+			if packageManagers[name] {
+				for _, arg := range args {
+					installs = installs || installVerbs[arg]
+				}
+			}
+		}
+		return true
+	})
+
+	if installs {
+		return nil
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] || known[name] || builtins[name] || strings.Contains(name, "/") {
+			continue
+		}
+		seen[name] = true
+		if _, err := lookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// packageManagers install programs when given one of installVerbs
+var (
+	packageManagers = map[string]bool{
+		"apt": true, "apt-get": true, "dnf": true, "yum": true, "pacman": true,
+		"apk": true, "zypper": true, "brew": true, "port": true, "snap": true,
+		"nix-env": true, "pip": true, "pip3": true, "pipx": true, "uv": true,
+		"npm": true, "pnpm": true, "yarn": true, "cargo": true, "go": true,
+		"gem": true, "conda": true, "winget": true, "choco": true, "scoop": true,
+	}
+	installVerbs = map[string]bool{"install": true, "add": true, "-S": true, "-i": true, "i": true, "get": true}
+)
+
+// wrappers run the command named after their own options
+var wrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nice": true, "nohup": true,
+	"time": true, "xargs": true, "exec": true, "command": true,
+}
+
+// commandName returns the program a simple command runs, looking past a
+// wrapper like sudo when no options make its arguments ambiguous, or "" if
+// the name is not a plain literal
+func commandName(args []string) string {
+	name := args[0]
+	for i := 1; i < len(args) && wrappers[path.Base(name)]; i++ {
+		switch arg := args[i]; {
+		case strings.HasPrefix(arg, "-"):
+			return name
+		case strings.Contains(arg, "="):
+			// env's assignments
+			continue
+		default:
+			name = arg
+		}
+	}
+	return name
+}
+
+// literals returns the words of a command, with "" for words that are not
+// plain literals, such as "$cmd"
+func literals(words []*syntax.Word) []string {
+	var out []string
+	for _, w := range words {
+		out = append(out, w.Lit())
+	}
+	return out
+}
+
+// builtins are shell builtins and keywords in bash, zsh and fish, which
+// are never on PATH
+var builtins = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		. : [ [[ alias bg bind break builtin caller cd command compgen complete
+		compopt continue declare dirs disown echo enable eval exec exit export
+		false fc fg getopts hash help history jobs kill let local logout mapfile
+		popd printf pushd pwd read readarray readonly return set shift shopt
+		source suspend test times trap true type typeset ulimit umask unalias
+		unset wait
+		autoload bindkey compdef emulate functions integer noglob print rehash
+		setopt unsetopt whence where which zle zmodload zstyle
+		abbr and argparse begin block contains count end fish_add_path funced
+		funcsave math not or set_color status string
+	`) {
+		builtins[name] = true
+	}
+}
@@ -0,0 +1,177 @@
+package validate
+
+import (
+	gocontext "context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// installed returns a LookPath that finds only names
+func installed(names ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, n := range names {
+			if n == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+}
+
+func TestIsProse(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"The ls command lists directory contents.", true},
+		{"Do you mean the current directory or your home directory?", true},
+		{"Yes!", true},
+		{"ls -la", false},
+		{"echo Done.", false},
+		{"Rscript analysis.R", false},
+		{"git commit -m 'Fix the build.'", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isProse(tt.text); got != tt.want {
+			t.Errorf("isProse(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	lookPath := installed("ls", "grep", "git", "apt-get", "bash")
+	tests := []struct {
+		name    string
+		command string
+		opts    Options
+		want    []Issue
+	}{
+		{"valid", "ls -la | grep go", Options{Shell: "bash"}, nil},
+		{"prose", "The ls command lists files.", Options{Shell: "bash"}, nil},
+		{"unknown binary", "fdfind -e go", Options{Shell: "bash"}, []Issue{{Message: "fdfind is not installed or not on PATH"}}},
+		{"behind sudo", "sudo fdfind -e go", Options{Shell: "bash"}, []Issue{{Message: "fdfind is not installed or not on PATH"}}},
+		{"alias", "ll", Options{Shell: "bash", Known: []string{"ll=ls -l"}}, nil},
+		{"function in script", "f() { ls; }; f", Options{Shell: "bash"}, nil},
+		{"builtin", "cd /tmp && pwd", Options{Shell: "bash"}, nil},
+		{"installs what it runs", "apt-get install -y ripgrep && rg TODO", Options{Shell: "bash"}, nil},
+		{"syntax error", "if true; then ls", Options{Shell: "bash"}, []Issue{{Syntax: true}}},
+		{"bash only syntax in sh", "files=(*.go) && ls", Options{Shell: "sh"}, []Issue{{Syntax: true}}},
+		{"unchecked shell", "Get-ChildItem | Where-Object Length -gt 1mb", Options{Shell: "pwsh"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.LookPath = lookPath
+			got := Check(gocontext.Background(), tt.command, tt.opts)
+			// Only the kind of parser errors is checked, not their wording
+			for i := range got {
+				if i < len(tt.want) && tt.want[i].Syntax && tt.want[i].Message == "" {
+					got[i].Message = ""
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckWithShell(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		stderr  string
+		err     error
+		wantRun []string
+		want    []Issue
+	}{
+		{
+			name:    "zsh accepts",
+			opts:    Options{Shell: "zsh", ShellPath: "/bin/zsh"},
+			wantRun: []string{"/bin/zsh", "-f", "-n"},
+		},
+		{
+			name:    "fish rejects",
+			opts:    Options{Shell: "fish"},
+			stderr:  "fish: Missing end to balance this if statement\nif true\n^\n",
+			err:     errors.New("exit status 127"),
+			wantRun: []string{"fish", "--no-execute"},
+			want:    []Issue{{Syntax: true, Message: "does not parse as fish: fish: Missing end to balance this if statement"}},
+		},
+		{
+			name: "shell binary missing",
+			opts: Options{Shell: "zsh", ShellPath: "/opt/zsh/bin/zsh"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			var script string
+			tt.opts.LookPath = installed("ls", "/bin/zsh", "fish")
+			tt.opts.RunShell = func(ctx gocontext.Context, bin string, args []string, stdin string) (string, error) {
+				ran, script = append([]string{bin}, args...), stdin
+				return tt.stderr, tt.err
+			}
+
+			got := Check(gocontext.Background(), "ls", tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(ran, tt.wantRun) {
+				t.Errorf("ran %q, want %q", ran, tt.wantRun)
+			}
+			if ran != nil && script != "ls\n" {
+				t.Errorf("shell read %q", script)
+			}
+		})
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	opts := Options{Shell: "bash", LookPath: installed("ls", "fd")}
+	bad := "fdfind -e go"
+	badIssues := Check(gocontext.Background(), bad, opts)
+
+	tests := []struct {
+		name       string
+		command    string
+		issues     []Issue
+		answer     string
+		err        error
+		wantAsked  bool
+		want       string
+		wantIssues int
+	}{
+		{"no issues", "ls", nil, "", nil, false, "ls", 0},
+		{"improved", bad, badIssues, "fd -e go", nil, true, "fd -e go", 0},
+		{"no better", bad, badIssues, "fdfind --extension go", nil, true, bad, 1},
+		{"worse", bad, badIssues, "fdfind -e go | rg x && if", nil, true, bad, 1},
+		{"empty answer", bad, badIssues, "", nil, true, bad, 1},
+		{"api error", bad, badIssues, "", errors.New("rate limited"), true, bad, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := false
+			var problems []string
+			got, issues, err := Correct(gocontext.Background(), tt.command, tt.issues, opts, func(p []string) (string, error) {
+				asked, problems = true, p
+				return tt.answer, tt.err
+			})
+			if asked != tt.wantAsked {
+				t.Errorf("asked for a correction: %v, want %v", asked, tt.wantAsked)
+			}
+			if asked && (len(problems) != len(tt.issues) || !strings.Contains(problems[0], "fdfind")) {
+				t.Errorf("passed problems %q", problems)
+			}
+			if got != tt.want || len(issues) != tt.wantIssues {
+				t.Errorf("Correct = %q with %v, want %q with %d issues", got, issues, tt.want, tt.wantIssues)
+			}
+			if err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}